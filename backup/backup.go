@@ -0,0 +1,376 @@
+// Package backup snapshots Deta Bases into files of a Deta Drive and restores them.
+//
+// A snapshot is a gzip compressed jsonl file with every item of a Base, stored next to a json
+// file with its metadata.
+//
+//	users, err := base.New(d, "users")
+//	if err != nil {
+//		return err
+//	}
+//	backups, err := drive.New(d, "backups")
+//	if err != nil {
+//		return err
+//	}
+//	bk := backup.New(backups)
+//
+//	// snapshot the users base
+//	if _, err := bk.Create(users, "users"); err != nil {
+//		return err
+//	}
+//
+//	// restore the latest snapshot into a new base
+//	snapshots, err := bk.List("users")
+//	if err != nil {
+//		return err
+//	}
+//	restored, err := base.New(d, "users_restored")
+//	if err != nil {
+//		return err
+//	}
+//	_, err = bk.Restore(snapshots[len(snapshots)-1], restored)
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/service/base"
+	"github.com/deta/deta-go/service/drive"
+)
+
+const (
+	// version of the snapshot format
+	formatVersion = 1
+	// default prefix of the snapshot files
+	defaultPrefix = "backups/"
+	// layout of snapshot ids, sortable in order of creation
+	idLayout = "20060102T150405.000000000Z"
+	// number of file names listed per request
+	listPageSize = 1000
+
+	dataExt = ".jsonl.gz"
+	metaExt = ".json"
+)
+
+// Backup creates, lists and restores snapshots of Bases stored in a Drive
+type Backup struct {
+	drive  drive.API
+	prefix string
+	now    func() time.Time
+}
+
+// Option is a functional option for a Backup
+type Option func(*Backup)
+
+// WithPrefix option for setting the prefix of the snapshot files, "backups/" by default
+func WithPrefix(prefix string) Option {
+	return func(bk *Backup) {
+		bk.prefix = prefix
+	}
+}
+
+// New returns a pointer to a new Backup storing snapshots in the drive
+func New(d drive.API, opts ...Option) *Backup {
+	bk := &Backup{
+		drive:  d,
+		prefix: defaultPrefix,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(bk)
+	}
+	return bk
+}
+
+// Snapshot metadata of a snapshot
+type Snapshot struct {
+	// id of the snapshot, unique and ordered by creation time for a name
+	ID string `json:"id"`
+	// name the snapshot was created with
+	Name string `json:"name"`
+	// version of the snapshot format
+	Version int `json:"version"`
+	// number of items in the snapshot
+	Items int `json:"items"`
+	// size of the compressed snapshot file in bytes
+	Size int64 `json:"size"`
+	// sha256 checksum of the compressed snapshot file, hex encoded
+	Checksum string `json:"checksum"`
+	// time the snapshot was created
+	Created time.Time `json:"created"`
+}
+
+// returns the name of the drive files of the snapshot without extension
+func (bk *Backup) fileName(name, id string) string {
+	return bk.prefix + name + "/" + id
+}
+
+// validates the name of a snapshot
+func validateName(name string) error {
+	if name == "" {
+		return deta.ErrEmptyName
+	}
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("%w: name contains '/'", deta.ErrBadSnapshot)
+	}
+	return nil
+}
+
+// Create snapshots all items of the base under the name.
+//
+// The items are streamed into the snapshot file, which is written before its metadata, so
+// that a failed snapshot is never listed.
+// Returns the metadata of the snapshot.
+func (bk *Backup) Create(b base.API, name string) (*Snapshot, error) {
+	return bk.CreateCtx(context.Background(), b, name)
+}
+
+// CreateCtx is like Create but uses the provided context for the requests.
+func (bk *Backup) CreateCtx(ctx context.Context, b base.API, name string) (*Snapshot, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	created := bk.now().UTC()
+	s := &Snapshot{
+		ID:      created.Format(idLayout),
+		Name:    name,
+		Version: formatVersion,
+		Created: created,
+	}
+	fileName := bk.fileName(name, s.ID)
+
+	w, err := bk.drive.CreateCtx(ctx, fileName+dataExt, "application/gzip")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	cw := &countWriter{w: io.MultiWriter(w, h)}
+	gz := gzip.NewWriter(cw)
+	s.Items, err = b.ExportCtx(ctx, gz, base.FormatJSONL, nil)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		if abortErr := w.CloseWithError(err); abortErr != nil {
+			return nil, &drive.AbortError{Err: err, AbortErr: abortErr}
+		}
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	s.Size = cw.n
+	s.Checksum = hex.EncodeToString(h.Sum(nil))
+
+	meta, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	_, err = bk.drive.PutCtx(ctx, &drive.PutInput{
+		Name:        fileName + metaExt,
+		Body:        strings.NewReader(string(meta)),
+		ContentType: "application/json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// counts the bytes written
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Get returns the metadata of the snapshot with the id created under the name.
+func (bk *Backup) Get(name, id string) (*Snapshot, error) {
+	return bk.GetCtx(context.Background(), name, id)
+}
+
+// GetCtx is like Get but uses the provided context for the request.
+func (bk *Backup) GetCtx(ctx context.Context, name, id string) (*Snapshot, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%w: empty id", deta.ErrBadSnapshot)
+	}
+
+	r, err := bk.drive.GetCtx(ctx, bk.fileName(name, id)+metaExt)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %v", deta.ErrBadSnapshot, err)
+	}
+	return &s, nil
+}
+
+// List returns the metadata of all snapshots created under the name, oldest first.
+func (bk *Backup) List(name string) ([]*Snapshot, error) {
+	return bk.ListCtx(context.Background(), name)
+}
+
+// ListCtx is like List but uses the provided context for the requests.
+func (bk *Backup) ListCtx(ctx context.Context, name string) ([]*Snapshot, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	prefix := bk.fileName(name, "")
+	var ids []string
+	last := ""
+	for {
+		lr, err := bk.drive.ListCtx(ctx, listPageSize, prefix, last)
+		if err != nil {
+			return nil, err
+		}
+		for _, fileName := range lr.Names {
+			id := strings.TrimPrefix(fileName, prefix)
+			if strings.HasSuffix(id, metaExt) && !strings.Contains(id, "/") {
+				ids = append(ids, strings.TrimSuffix(id, metaExt))
+			}
+		}
+		if lr.Paging == nil || lr.Paging.Last == nil || *lr.Paging.Last == "" {
+			break
+		}
+		last = *lr.Paging.Last
+	}
+	sort.Strings(ids)
+
+	snapshots := make([]*Snapshot, 0, len(ids))
+	for _, id := range ids {
+		s, err := bk.GetCtx(ctx, name, id)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+// Restore puts the items of the snapshot in the base.
+//
+// The snapshot file is downloaded to a temporary file and verified against the checksum before
+// any item is put. The items keep their keys and overwrite existing items with the same keys.
+// The options are passed to the Import of the items. An error matching deta.ErrBadSnapshot is
+// returned if the number of put items does not match the snapshot.
+func (bk *Backup) Restore(s *Snapshot, b base.API, opts ...base.ImportOption) (*base.ImportOutput, error) {
+	return bk.RestoreCtx(context.Background(), s, b, opts...)
+}
+
+// RestoreCtx is like Restore but uses the provided context for the requests.
+func (bk *Backup) RestoreCtx(ctx context.Context, s *Snapshot, b base.API, opts ...base.ImportOption) (*base.ImportOutput, error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: nil snapshot", deta.ErrBadSnapshot)
+	}
+	if s.Version != formatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", deta.ErrBadSnapshot, s.Version)
+	}
+	if err := validateName(s.Name); err != nil {
+		return nil, err
+	}
+	dataName := bk.fileName(s.Name, s.ID) + dataExt
+
+	f, err := bk.download(ctx, dataName, s)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", deta.ErrBadSnapshot, err)
+	}
+	out, err := b.ImportCtx(ctx, gz, base.FormatJSONL, opts...)
+	if err != nil {
+		return out, err
+	}
+	if out.Processed != s.Items {
+		return out, fmt.Errorf("%w: %d items were put instead of %d", deta.ErrBadSnapshot, out.Processed, s.Items)
+	}
+	return out, nil
+}
+
+// downloads the snapshot file to a temporary file and verifies its size and checksum
+//
+// Returns the temporary file at its start, the caller closes and removes it.
+func (bk *Backup) download(ctx context.Context, dataName string, s *Snapshot) (*os.File, error) {
+	r, err := bk.drive.GetCtx(ctx, dataName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := ioutil.TempFile("", "deta-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	if err := spool(f, r, s); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// copies the snapshot file to f, verifying its size and checksum, and seeks f to its start
+func spool(f *os.File, r io.Reader, s *Snapshot) error {
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return err
+	}
+	if n != s.Size {
+		return fmt.Errorf("%w: size %d of the snapshot file does not match %d", deta.ErrBadSnapshot, n, s.Size)
+	}
+	if checksum := hex.EncodeToString(h.Sum(nil)); checksum != s.Checksum {
+		return fmt.Errorf("%w: checksum %s of the snapshot file does not match %s", deta.ErrBadSnapshot, checksum, s.Checksum)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Delete deletes the files of the snapshot.
+func (bk *Backup) Delete(s *Snapshot) error {
+	return bk.DeleteCtx(context.Background(), s)
+}
+
+// DeleteCtx is like Delete but uses the provided context for the requests.
+func (bk *Backup) DeleteCtx(ctx context.Context, s *Snapshot) error {
+	if s == nil {
+		return fmt.Errorf("%w: nil snapshot", deta.ErrBadSnapshot)
+	}
+	if err := validateName(s.Name); err != nil {
+		return err
+	}
+	fileName := bk.fileName(s.Name, s.ID)
+	// the metadata is deleted first, so that a partially deleted snapshot is not listed
+	if _, err := bk.drive.DeleteCtx(ctx, fileName+metaExt); err != nil {
+		return err
+	}
+	_, err := bk.drive.DeleteCtx(ctx, fileName+dataExt)
+	return err
+}
@@ -0,0 +1,226 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
+	"github.com/deta/deta-go/service/base"
+	"github.com/deta/deta-go/service/base/basemock"
+	"github.com/deta/deta-go/service/drive"
+	"github.com/deta/deta-go/service/drive/drivemock"
+)
+
+func setup(t *testing.T) (*detatest.Server, *Backup, *base.Base) {
+	srv := detatest.NewServer()
+	d, err := srv.Deta()
+	if err != nil {
+		t.Fatalf("Failed to create deta instance: %v", err)
+	}
+	dr, err := drive.New(d, "backups")
+	if err != nil {
+		t.Fatalf("Failed to create drive: %v", err)
+	}
+	b, err := base.New(d, "users")
+	if err != nil {
+		t.Fatalf("Failed to create base: %v", err)
+	}
+	return srv, New(dr), b
+}
+
+func TestBackup(t *testing.T) {
+	srv, bk, users := setup(t)
+	defer srv.Close()
+	d, _ := srv.Deta()
+
+	var items []map[string]interface{}
+	for n := 0; n < 40; n++ {
+		items = append(items, map[string]interface{}{
+			"key":  fmt.Sprintf("user_%02d", n),
+			"age":  float64(n),
+			"tags": []interface{}{"a", "b"},
+		})
+	}
+	if _, err := users.BulkPut(items); err != nil {
+		t.Fatalf("Failed to put items: %v", err)
+	}
+
+	now := time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)
+	bk.now = func() time.Time { return now }
+	first, err := bk.Create(users, "users")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	if first.ID != "20210304T050607.000000008Z" || first.Name != "users" || first.Items != len(items) ||
+		first.Version != formatVersion || !first.Created.Equal(now) || first.Size == 0 || len(first.Checksum) != 64 {
+		t.Errorf("Unexpected snapshot %+v", first)
+	}
+
+	users.Delete("user_00")
+	now = now.Add(time.Hour)
+	second, err := bk.Create(users, "users")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	if second.Items != len(items)-1 {
+		t.Errorf("Unexpected number of items in snapshot. Expected: %d Got: %d", len(items)-1, second.Items)
+	}
+
+	snapshots, err := bk.List("users")
+	if err != nil {
+		t.Fatalf("Failed to list snapshots: %v", err)
+	}
+	if len(snapshots) != 2 || !reflect.DeepEqual(*snapshots[0], *first) || !reflect.DeepEqual(*snapshots[1], *second) {
+		t.Errorf("Unexpected snapshots. Expected: %+v %+v Got: %+v", first, second, snapshots)
+	}
+	if snapshots, err := bk.List("orders"); err != nil || len(snapshots) != 0 {
+		t.Errorf("Unexpected snapshots of other name %v, %v", snapshots, err)
+	}
+
+	restored, _ := base.New(d, "users_restored")
+	out, err := bk.Restore(first, restored)
+	if err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	if out.Processed != len(items) {
+		t.Errorf("Unexpected number of restored items. Expected: %d Got: %d", len(items), out.Processed)
+	}
+	var restoredItems []map[string]interface{}
+	if err := restored.FetchAll(nil, &restoredItems); err != nil {
+		t.Fatalf("Failed to fetch restored items: %v", err)
+	}
+	if !reflect.DeepEqual(restoredItems, items) {
+		t.Errorf("Restored items not equal to snapshot items. Expected: %v Got: %v", items, restoredItems)
+	}
+
+	if err := bk.Delete(first); err != nil {
+		t.Fatalf("Failed to delete snapshot: %v", err)
+	}
+	if _, err := bk.Get("users", first.ID); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+	if snapshots, err := bk.List("users"); err != nil || len(snapshots) != 1 {
+		t.Errorf("Unexpected snapshots after delete %v, %v", snapshots, err)
+	}
+}
+
+func TestRestoreCorrupted(t *testing.T) {
+	srv, bk, users := setup(t)
+	defer srv.Close()
+
+	if _, err := users.Put(map[string]interface{}{"key": "a"}); err != nil {
+		t.Fatalf("Failed to put item: %v", err)
+	}
+	s, err := bk.Create(users, "users")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	// overwrite the snapshot file
+	_, err = bk.drive.Put(&drive.PutInput{
+		Name: bk.fileName("users", s.ID) + dataExt,
+		Body: strings.NewReader("corrupted"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to overwrite snapshot file: %v", err)
+	}
+	users.Delete("a")
+	if _, err := bk.Restore(s, users); !errors.Is(err, deta.ErrBadSnapshot) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadSnapshot, err)
+	}
+	var dest map[string]interface{}
+	if err := users.Get("a", &dest); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Item restored from corrupted snapshot")
+	}
+
+	unsupported := *s
+	unsupported.Version = formatVersion + 1
+	if _, err := bk.Restore(&unsupported, users); !errors.Is(err, deta.ErrBadSnapshot) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadSnapshot, err)
+	}
+
+	for _, name := range []string{"", "a/b"} {
+		if _, err := bk.Create(users, name); err == nil {
+			t.Errorf("Expected an error for snapshot name %q", name)
+		}
+	}
+}
+
+func TestCreateAbortsFailedExport(t *testing.T) {
+	exportErr := errors.New("export failed")
+	users := &basemock.Base{
+		ExportCtxFunc: func(ctx context.Context, w io.Writer, format base.Format, q base.Query, opts ...base.ExportOption) (int, error) {
+			w.Write([]byte(`{"key": "a"}`))
+			return 1, exportErr
+		},
+	}
+	var created string
+	w := drivemock.NewWriter("")
+	backups := &drivemock.Drive{
+		CreateCtxFunc: func(ctx context.Context, name, contentType string) (drive.FileWriter, error) {
+			created = name
+			return w, nil
+		},
+	}
+	bk := New(backups)
+	bk.now = func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC) }
+
+	// the metadata is not put, PutCtx of the mock would panic
+	if _, err := bk.Create(users, "users"); !errors.Is(err, exportErr) {
+		t.Fatalf("Unexpected error. Expected: %v Got: %v", exportErr, err)
+	}
+	if created != "backups/users/20210304T050607.000000008Z.jsonl.gz" {
+		t.Errorf("Unexpected snapshot file. Got: %s", created)
+	}
+	if closed, err := w.Closed(); !closed || err != exportErr {
+		t.Errorf("Unexpected close of the snapshot file. Expected: true %v Got: %v %v", exportErr, closed, err)
+	}
+}
+
+// a drive counting the downloads of files
+type countingDrive struct {
+	drive.API
+	gets int
+}
+
+func (d *countingDrive) GetCtx(ctx context.Context, name string) (io.ReadCloser, error) {
+	d.gets++
+	return d.API.GetCtx(ctx, name)
+}
+
+func TestRestoreVerifiesSingleDownload(t *testing.T) {
+	srv, bk, users := setup(t)
+	defer srv.Close()
+
+	if _, err := users.PutMany([]map[string]interface{}{{"key": "a"}, {"key": "b"}}); err != nil {
+		t.Fatalf("Failed to put items: %v", err)
+	}
+	s, err := bk.Create(users, "users")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	counter := &countingDrive{API: bk.drive}
+	bk.drive = counter
+	out, err := bk.Restore(s, users)
+	if err != nil || out.Processed != 2 {
+		t.Fatalf("Unexpected restore result. Expected: %d Got: %v, %v", 2, out, err)
+	}
+	if counter.gets != 1 {
+		t.Errorf("Unexpected downloads of the snapshot file. Expected: %d Got: %d", 1, counter.gets)
+	}
+
+	// the number of put items must match the snapshot
+	mismatched := *s
+	mismatched.Items = 3
+	if _, err := bk.Restore(&mismatched, users); !errors.Is(err, deta.ErrBadSnapshot) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadSnapshot, err)
+	}
+}
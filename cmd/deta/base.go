@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/service/base"
+)
+
+// runs a base command
+func (c *cli) base(d *deta.Deta, name, cmd string, args []string) error {
+	b, err := base.New(d, name)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "get":
+		return c.baseGet(b, args)
+	case "put":
+		return c.basePut(b, args)
+	case "insert":
+		return c.baseInsert(b, args)
+	case "update":
+		return c.baseUpdate(b, args)
+	case "delete":
+		return c.baseDelete(b, args)
+	case "query":
+		return c.baseQuery(b, args)
+	default:
+		return usageErr("unknown base command '%s'", cmd)
+	}
+}
+
+func (c *cli) baseGet(b *base.Base, args []string) error {
+	fs := c.flags("base", "get")
+	format := fs.String("o", "json", "output format: json, jsonl or table")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErr("no keys provided")
+	}
+
+	items := make([]map[string]interface{}, 0, fs.NArg())
+	for _, key := range fs.Args() {
+		var item map[string]interface{}
+		if err := b.GetCtx(c.ctx, key, &item); err != nil {
+			return fmt.Errorf("failed to get item '%s': %w", key, err)
+		}
+		items = append(items, item)
+	}
+	return writeItems(c.stdout, *format, items)
+}
+
+// decodes the items of json objects, lists of json objects or jsonl
+func decodeItems(r io.Reader) ([]map[string]interface{}, error) {
+	var items []map[string]interface{}
+	dec := json.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode items: %w", err)
+		}
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			items = append(items, val)
+		case []interface{}:
+			for _, e := range val {
+				item, ok := e.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("%w: item is not an object", deta.ErrBadItem)
+				}
+				items = append(items, item)
+			}
+		default:
+			return nil, fmt.Errorf("%w: item is not an object", deta.ErrBadItem)
+		}
+	}
+}
+
+func (c *cli) basePut(b *base.Base, args []string) error {
+	fs := c.flags("base", "put")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	var r io.Reader
+	switch fs.NArg() {
+	case 0:
+		r = c.stdin
+	case 1:
+		r = strings.NewReader(fs.Arg(0))
+	default:
+		return usageErr("too many arguments")
+	}
+
+	items, err := decodeItems(r)
+	if err != nil {
+		return err
+	}
+	results, err := b.BulkPutCtx(c.ctx, items)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for n, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(c.stderr, "failed to put item %d: %v\n", n+1, res.Err)
+			failed++
+			continue
+		}
+		fmt.Fprintln(c.stdout, res.Key)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d item(s)", deta.ErrItemsFailed, failed, len(results))
+	}
+	return nil
+}
+
+func (c *cli) baseInsert(b *base.Base, args []string) error {
+	fs := c.flags("base", "insert")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErr("insert takes a single item")
+	}
+
+	var item map[string]interface{}
+	if err := json.Unmarshal([]byte(fs.Arg(0)), &item); err != nil {
+		return fmt.Errorf("%w: %v", deta.ErrBadItem, err)
+	}
+	key, err := b.InsertCtx(c.ctx, item)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, key)
+	return nil
+}
+
+func (c *cli) baseUpdate(b *base.Base, args []string) error {
+	fs := c.flags("base", "update")
+	del := fs.String("delete", "", "comma-separated fields to delete")
+	increment := fs.String("increment", "", "json object of fields to increment by a value")
+	appendValues := fs.String("append", "", "json object of values to append to list fields")
+	prependValues := fs.String("prepend", "", "json object of values to prepend to list fields")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageErr("update takes a key and optional updates")
+	}
+
+	updates := base.Updates{}
+	if fs.NArg() == 2 {
+		if err := json.Unmarshal([]byte(fs.Arg(1)), &updates); err != nil {
+			return fmt.Errorf("bad updates: %v", err)
+		}
+	}
+	for _, field := range strings.Split(*del, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			updates[field] = b.Util.Trim()
+		}
+	}
+	utils := []struct {
+		flag string
+		util func(v interface{}) interface{}
+	}{
+		{*increment, func(v interface{}) interface{} { return b.Util.Increment(v) }},
+		{*appendValues, func(v interface{}) interface{} { return b.Util.Append(v) }},
+		{*prependValues, func(v interface{}) interface{} { return b.Util.Prepend(v) }},
+	}
+	for _, u := range utils {
+		if u.flag == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(u.flag), &m); err != nil {
+			return fmt.Errorf("bad updates: %v", err)
+		}
+		for field, v := range m {
+			updates[field] = u.util(v)
+		}
+	}
+	if len(updates) == 0 {
+		return usageErr("no updates provided")
+	}
+
+	return b.UpdateCtx(c.ctx, fs.Arg(0), updates)
+}
+
+func (c *cli) baseDelete(b *base.Base, args []string) error {
+	fs := c.flags("base", "delete")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageErr("no keys provided")
+	}
+	for _, key := range fs.Args() {
+		if err := b.DeleteCtx(c.ctx, key); err != nil {
+			return fmt.Errorf("failed to delete item '%s': %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (c *cli) baseQuery(b *base.Base, args []string) error {
+	fs := c.flags("base", "query")
+	format := fs.String("o", "json", "output format: json, jsonl or table")
+	limit := fs.Int("limit", 0, "maximum number of items, all items if not set")
+	desc := fs.Bool("desc", false, "sort items in descending order of their keys")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	q, err := parseQuery(fs.Args())
+	if err != nil {
+		return err
+	}
+	opts := []base.IterOption{base.WithLimit(*limit)}
+	if *desc {
+		opts = append(opts, base.WithDesc())
+	}
+
+	// stream jsonl, other formats need all items
+	it := b.IterCtx(c.ctx, q, opts...)
+	if *format == "jsonl" {
+		enc := json.NewEncoder(c.stdout)
+		for it.Next() {
+			var item map[string]interface{}
+			if err := it.Scan(&item); err != nil {
+				return err
+			}
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	}
+	items := make([]map[string]interface{}, 0)
+	if err := it.ScanAll(&items); err != nil {
+		return err
+	}
+	return writeItems(c.stdout, *format, items)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/service/drive"
+)
+
+// runs a drive command
+func (c *cli) drive(d *deta.Deta, name, cmd string, args []string) error {
+	dr, err := drive.New(d, name)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "ls":
+		return c.driveLs(dr, args)
+	case "get":
+		return c.driveGet(dr, args)
+	case "put":
+		return c.drivePut(dr, args)
+	case "rm":
+		return c.driveRm(dr, args)
+	case "cp":
+		return c.driveCp(dr, args)
+	default:
+		return usageErr("unknown drive command '%s'", cmd)
+	}
+}
+
+// maximum number of names listed per request
+const listPageSize = 1000
+
+func (c *cli) driveLs(dr *drive.Drive, args []string) error {
+	fs := c.flags("drive", "ls")
+	prefix := fs.String("prefix", "", "only list names with the prefix")
+	limit := fs.Int("limit", 0, "maximum number of names, all names if not set")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageErr("too many arguments")
+	}
+
+	listed := 0
+	last := ""
+	for {
+		pageSize := listPageSize
+		if left := *limit - listed; *limit > 0 && left < listPageSize {
+			pageSize = left
+		}
+		lr, err := dr.ListCtx(c.ctx, pageSize, *prefix, last)
+		if err != nil {
+			return err
+		}
+		for _, name := range lr.Names {
+			fmt.Fprintln(c.stdout, name)
+		}
+		listed += len(lr.Names)
+		if (*limit > 0 && listed >= *limit) || lr.Paging == nil || lr.Paging.Last == nil || *lr.Paging.Last == "" {
+			return nil
+		}
+		last = *lr.Paging.Last
+	}
+}
+
+func (c *cli) driveGet(dr *drive.Drive, args []string) error {
+	fs := c.flags("drive", "get")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageErr("get takes a name and an optional file")
+	}
+
+	rc, err := dr.GetCtx(c.ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	file := fs.Arg(1)
+	if file == "" || file == "-" {
+		_, err = io.Copy(c.stdout, rc)
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c *cli) drivePut(dr *drive.Drive, args []string) error {
+	fs := c.flags("drive", "put")
+	contentType := fs.String("content-type", "", "content type of the file, detected from the extension if not set")
+	concurrency := fs.Int("concurrency", 1, "number of parts uploaded concurrently")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageErr("put takes a file and an optional name")
+	}
+
+	file, name := fs.Arg(0), fs.Arg(1)
+	if name == "" {
+		if file == "-" {
+			return usageErr("a name is required to put from stdin")
+		}
+		name = filepath.Base(file)
+	}
+	if *contentType == "" {
+		*contentType = mime.TypeByExtension(path.Ext(name))
+	}
+
+	var body io.Reader = c.stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		body = f
+	}
+
+	name, err := dr.PutCtx(c.ctx, &drive.PutInput{
+		Name:        name,
+		Body:        body,
+		ContentType: *contentType,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, name)
+	return nil
+}
+
+func (c *cli) driveRm(dr *drive.Drive, args []string) error {
+	fs := c.flags("drive", "rm")
+	prefix := fs.String("prefix", "", "delete all files with the prefix")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if (fs.NArg() == 0) == (*prefix == "") {
+		return usageErr("rm takes either names or a prefix")
+	}
+
+	var out *drive.DeleteManyOutput
+	var err error
+	if *prefix != "" {
+		out, err = dr.DeletePrefixCtx(c.ctx, *prefix)
+	} else {
+		out, err = dr.DeleteManyCtx(c.ctx, fs.Args())
+	}
+	if err != nil {
+		return err
+	}
+	failed := make([]string, 0, len(out.Failed))
+	for name := range out.Failed {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+	for _, name := range failed {
+		fmt.Fprintf(c.stderr, "failed to delete %s: %s\n", name, out.Failed[name])
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete %d file(s)", len(failed))
+	}
+	return nil
+}
+
+func (c *cli) driveCp(dr *drive.Drive, args []string) error {
+	fs := c.flags("drive", "cp")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageErr("cp takes a source and a destination name")
+	}
+	_, err := dr.CopyCtx(c.ctx, fs.Arg(0), fs.Arg(1))
+	return err
+}
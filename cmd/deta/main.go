@@ -0,0 +1,163 @@
+// Command deta is a command-line tool for Deta Base and Drive.
+//
+// Usage:
+//
+//	deta [-project-key key] base <base_name> <command> [arguments]
+//	deta [-project-key key] drive <drive_name> <command> [arguments]
+//
+// The project key is read from the DETA_PROJECT_KEY environment variable unless provided with -project-key.
+//
+// Base commands:
+//
+//	get [-o format] <key>...                 get items
+//	put [item]                               put items given as json or jsonl, read from stdin if omitted
+//	insert <item>                            insert an item
+//	update [flags] <key> [updates]           update an item, the updates set fields
+//	delete <key>...                          delete items
+//	query [-limit n] [-desc] [-o format] [query]
+//	                                         fetch the items matching a query
+//
+// Items are printed as json, jsonl or a table with -o.
+//
+// A query is either json, an object or a list of objects as in base.Query, or a list of conditions
+// in the form field[?operator]=value. The conditions are ANDed, the word 'or' separates ORed conditions.
+// Values are parsed as json if valid, as strings otherwise.
+//
+//	deta base users query 'age?lt=32' active=true or 'name?pfx=j'
+//	deta base users query '[{"age?lt": 32, "active": true}, {"name?pfx": "j"}]'
+//
+// Drive commands:
+//
+//	ls [-prefix p] [-limit n]                list file names
+//	get <name> [file]                        download a file, to stdout if file is omitted or '-'
+//	put [-content-type t] <file> [name]      upload a file, from stdin if file is '-'
+//	rm <name>... | rm -prefix p              delete files, or all files with the prefix
+//	cp <src> <dst>                           copy a file in the drive
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/deta/deta-go/deta"
+)
+
+const usage = `usage:
+	deta [-project-key key] base <base_name> <command> [arguments]
+	deta [-project-key key] drive <drive_name> <command> [arguments]
+
+base commands: get, put, insert, update, delete, query
+drive commands: ls, get, put, rm, cp
+
+Run 'go doc github.com/deta/deta-go/cmd/deta' for details.
+`
+
+// usageError is returned for invalid arguments
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// returns a usageError
+func usageErr(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
+
+// errFlags is returned for invalid flags of a command, already reported by the flag set
+var errFlags = errors.New("bad flags")
+
+// a run of the command
+type cli struct {
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// runs the command with the arguments and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("deta", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+	}
+	projectKey := fs.String("project-key", "", "project key, DETA_PROJECT_KEY if not set")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 3 {
+		fs.Usage()
+		return 2
+	}
+
+	var opts []deta.ConfigOption
+	if *projectKey != "" {
+		opts = append(opts, deta.WithProjectKey(*projectKey))
+	}
+	d, err := deta.New(opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "deta: %v\n", err)
+		return 1
+	}
+
+	c := &cli{ctx: ctx, stdin: stdin, stdout: stdout, stderr: stderr}
+	service, name, cmd, cmdArgs := fs.Arg(0), fs.Arg(1), fs.Arg(2), fs.Args()[3:]
+	switch service {
+	case "base":
+		err = c.base(d, name, cmd, cmdArgs)
+	case "drive":
+		err = c.drive(d, name, cmd, cmdArgs)
+	default:
+		err = usageErr("unknown service '%s'", service)
+	}
+
+	var ue *usageError
+	if errors.As(err, &ue) {
+		fmt.Fprintf(stderr, "deta: %v\n\n%s", ue, usage)
+		return 2
+	}
+	if err == errFlags {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "deta: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// returns a new flag set of a command
+func (c *cli) flags(service, cmd string) *flag.FlagSet {
+	fs := flag.NewFlagSet(service+" "+cmd, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parses the flags of a command
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errFlags
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
+	"github.com/deta/deta-go/service/base"
+)
+
+func TestMain(m *testing.M) {
+	srv := detatest.NewServer()
+	os.Setenv("DETA_PROJECT_KEY", detatest.ProjectKey)
+	os.Setenv("DETA_BASE_ROOT_ENDPOINT", srv.BaseEndpoint())
+	os.Setenv("DETA_DRIVE_ROOT_ENDPOINT", srv.DriveEndpoint())
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
+
+// runs the command and returns its exit code, stdout and stderr
+func runCmd(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestParseQuery(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected base.Query
+	}{
+		{nil, nil},
+		{
+			[]string{"age?lt=32", "active=true", "or", "name?pfx=12"},
+			base.Query{{"age?lt": float64(32), "active": true}, {"name?pfx": "12"}},
+		},
+		{
+			[]string{"address.city=new york", "age?r=[18, 65]", "likes?contains=go"},
+			base.Query{{"address.city": "new york", "age?r": []interface{}{float64(18), float64(65)}, "likes?contains": "go"}},
+		},
+		{
+			[]string{`{"age?gte": 18}`},
+			base.Query{{"age?gte": float64(18)}},
+		},
+		{
+			[]string{`[{"a": 1}, {"b": "c"}]`},
+			base.Query{{"a": float64(1)}, {"b": "c"}},
+		},
+	}
+	for _, tc := range testCases {
+		q, err := parseQuery(tc.args)
+		if err != nil {
+			t.Fatalf("Failed to parse query %v: %v", tc.args, err)
+		}
+		if !reflect.DeepEqual(q, tc.expected) {
+			t.Errorf("Parsed query not as expected. Expected: %v Got: %v", tc.expected, q)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"or", "a=1"},
+		{"a=1", "or"},
+		{"a=1", "or", "or", "b=1"},
+		{"a"},
+		{"a?unknown=1"},
+		{"a?lt=true"},
+		{"a?r=[1]"},
+		{`{"a": }`},
+	} {
+		if _, err := parseQuery(args); !errors.Is(err, deta.ErrBadQuery) {
+			t.Errorf("Unexpected error for query %v. Expected: %v Got: %v", args, deta.ErrBadQuery, err)
+		}
+	}
+}
+
+func TestBaseCommands(t *testing.T) {
+	code, out, errOut := runCmd(`{"key": "a", "name": "alice", "age": 30}
+{"key": "b", "name": "bob", "age": 20}
+[{"key": "c", "name": "carol", "age": 40}]`, "base", "users", "put")
+	if code != 0 || out != "a\nb\nc\n" {
+		t.Fatalf("Unexpected put result. Expected: %v %q Got: %v %q (stderr: %s)", 0, "a\nb\nc\n", code, out, errOut)
+	}
+
+	code, out, errOut = runCmd("", "base", "users", "insert", `{"key": "d", "name": "dave", "age": 50}`)
+	if code != 0 || out != "d\n" {
+		t.Fatalf("Unexpected insert result. Expected: %v %q Got: %v %q (stderr: %s)", 0, "d\n", code, out, errOut)
+	}
+	if code, _, _ = runCmd("", "base", "users", "insert", `{"key": "d"}`); code != 1 {
+		t.Errorf("Unexpected exit code of conflicting insert. Expected: %v Got: %v", 1, code)
+	}
+
+	code, _, errOut = runCmd("", "base", "users", "update", "-increment", `{"age": 1}`, "-delete", "name", "a", `{"active": true}`)
+	if code != 0 {
+		t.Fatalf("Failed to update item: %s", errOut)
+	}
+	code, out, _ = runCmd("", "base", "users", "get", "-o", "jsonl", "a")
+	expected := `{"active":true,"age":31,"key":"a"}` + "\n"
+	if code != 0 || out != expected {
+		t.Errorf("Unexpected get result. Expected: %q Got: %q", expected, out)
+	}
+
+	code, out, errOut = runCmd("", "base", "users", "query", "-desc", "age?gte=30", "or", "name=bob")
+	if code != 0 {
+		t.Fatalf("Failed to query items: %s", errOut)
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		t.Fatalf("Failed to decode query output: %v", err)
+	}
+	var keys []string
+	for _, item := range items {
+		keys = append(keys, item["key"].(string))
+	}
+	if !reflect.DeepEqual(keys, []string{"d", "c", "b", "a"}) {
+		t.Errorf("Unexpected query result. Expected: %v Got: %v", []string{"d", "c", "b", "a"}, keys)
+	}
+
+	code, out, _ = runCmd("", "base", "users", "query", "-o", "table", "-limit", "2", "age?lt=45")
+	expected = "key  active  age  name\na    true    31   \nb            20   bob\n"
+	if code != 0 || out != expected {
+		t.Errorf("Unexpected table output. Expected: %q Got: %q", expected, out)
+	}
+
+	if code, _, errOut = runCmd("", "base", "users", "delete", "a", "b", "c", "d"); code != 0 {
+		t.Fatalf("Failed to delete items: %s", errOut)
+	}
+	if code, _, _ = runCmd("", "base", "users", "get", "a"); code != 1 {
+		t.Errorf("Unexpected exit code of get of deleted item. Expected: %v Got: %v", 1, code)
+	}
+}
+
+func TestDriveCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deta-cli")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "hello.txt")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if code, out, errOut := runCmd("", "drive", "files", "put", file); code != 0 || out != "hello.txt\n" {
+		t.Fatalf("Unexpected put result. Expected: %v %q Got: %v %q (stderr: %s)", 0, "hello.txt\n", code, out, errOut)
+	}
+	if code, _, errOut := runCmd("from stdin", "drive", "files", "put", "-", "docs/stdin.txt"); code != 0 {
+		t.Fatalf("Failed to put file from stdin: %s", errOut)
+	}
+	if code, _, errOut := runCmd("", "drive", "files", "cp", "hello.txt", "docs/copy.txt"); code != 0 {
+		t.Fatalf("Failed to copy file: %s", errOut)
+	}
+
+	code, out, _ := runCmd("", "drive", "files", "ls")
+	if expected := "docs/copy.txt\ndocs/stdin.txt\nhello.txt\n"; code != 0 || out != expected {
+		t.Errorf("Unexpected ls result. Expected: %q Got: %q", expected, out)
+	}
+	code, out, _ = runCmd("", "drive", "files", "ls", "-prefix", "docs/", "-limit", "1")
+	if expected := "docs/copy.txt\n"; code != 0 || out != expected {
+		t.Errorf("Unexpected ls result. Expected: %q Got: %q", expected, out)
+	}
+
+	code, out, _ = runCmd("", "drive", "files", "get", "docs/copy.txt")
+	if code != 0 || out != "hello" {
+		t.Errorf("Unexpected get result. Expected: %q Got: %q", "hello", out)
+	}
+	downloaded := filepath.Join(dir, "downloaded.txt")
+	if code, _, errOut := runCmd("", "drive", "files", "get", "docs/stdin.txt", downloaded); code != 0 {
+		t.Fatalf("Failed to get file: %s", errOut)
+	}
+	if content, _ := ioutil.ReadFile(downloaded); string(content) != "from stdin" {
+		t.Errorf("Unexpected downloaded content. Expected: %q Got: %q", "from stdin", content)
+	}
+
+	if code, _, errOut := runCmd("", "drive", "files", "rm", "hello.txt"); code != 0 {
+		t.Fatalf("Failed to remove files: %s", errOut)
+	}
+	if code, _, errOut := runCmd("", "drive", "files", "rm", "-prefix", "docs/"); code != 0 {
+		t.Fatalf("Failed to remove files with prefix: %s", errOut)
+	}
+	if code, out, _ = runCmd("", "drive", "files", "ls"); code != 0 || out != "" {
+		t.Errorf("Unexpected ls result after rm. Expected: %q Got: %q", "", out)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"base", "users"},
+		{"queue", "users", "get"},
+		{"base", "users", "unknown"},
+		{"base", "users", "get"},
+		{"base", "users", "get", "-unknown", "a"},
+		{"drive", "files", "cp", "a"},
+		{"drive", "files", "rm"},
+		{"drive", "files", "rm", "-prefix", "a", "b"},
+	} {
+		if code, _, _ := runCmd("", args...); code != 2 {
+			t.Errorf("Unexpected exit code for %v. Expected: %v Got: %v", args, 2, code)
+		}
+	}
+}
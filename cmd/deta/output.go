@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// writes the items in the format
+func writeItems(w io.Writer, format string, items []map[string]interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		return writeTable(w, items)
+	default:
+		return usageErr("unknown output format '%s'", format)
+	}
+}
+
+// writes the items as a table, with a column for every field
+//
+// The key is the first column, other fields are sorted by name.
+func writeTable(w io.Writer, items []map[string]interface{}) error {
+	fields := make(map[string]bool)
+	for _, item := range items {
+		for field := range item {
+			if field != "key" {
+				fields[field] = true
+			}
+		}
+	}
+	columns := make([]string, 0, len(fields)+1)
+	for field := range fields {
+		columns = append(columns, field)
+	}
+	sort.Strings(columns)
+	columns = append([]string{"key"}, columns...)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, item := range items {
+		cells := make([]string, len(columns))
+		for n, column := range columns {
+			cells[n] = cell(item[column])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// formats a value as a table cell
+func cell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.NewReplacer("\t", " ", "\n", " ").Replace(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
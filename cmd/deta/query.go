@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/service/base"
+)
+
+// parses a query from json or a list of conditions
+//
+// Conditions are in the form field[?operator]=value, the word 'or' separates ORed conditions.
+func parseQuery(args []string) (base.Query, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if len(args) == 1 {
+		if s := strings.TrimSpace(args[0]); strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+			return parseJSONQuery(s)
+		}
+	}
+
+	var qb *base.QueryBuilder
+	and := true
+	for _, arg := range args {
+		if strings.EqualFold(arg, "or") {
+			if qb == nil || !and {
+				return nil, fmt.Errorf("%w: misplaced 'or'", deta.ErrBadQuery)
+			}
+			and = false
+			continue
+		}
+
+		n := strings.Index(arg, "=")
+		if n < 0 {
+			return nil, fmt.Errorf("%w: condition '%s' is not in the form field[?operator]=value", deta.ErrBadQuery, arg)
+		}
+		field, op, raw := arg[:n], "", arg[n+1:]
+		if i := strings.LastIndex(field, "?"); i >= 0 {
+			field, op = field[:i], field[i+1:]
+		}
+
+		var cond *base.Condition
+		switch {
+		case qb == nil:
+			cond = base.Where(field)
+		case and:
+			cond = qb.And(field)
+		default:
+			cond = qb.OrWhere(field)
+		}
+		and = true
+
+		var err error
+		qb, err = applyCondition(cond, op, raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !and {
+		return nil, fmt.Errorf("%w: misplaced 'or'", deta.ErrBadQuery)
+	}
+	return qb.Query()
+}
+
+// parses a query from a json object or list of objects
+func parseJSONQuery(s string) (base.Query, error) {
+	if strings.HasPrefix(s, "{") {
+		s = "[" + s + "]"
+	}
+	var q base.Query
+	if err := json.Unmarshal([]byte(s), &q); err != nil {
+		return nil, fmt.Errorf("%w: %v", deta.ErrBadQuery, err)
+	}
+	return q, nil
+}
+
+// parses a value as json, or returns it as a string if it is not valid json
+func parseValue(raw string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return v
+}
+
+// applies the condition with the operator on the raw value
+func applyCondition(cond *base.Condition, op, raw string) (*base.QueryBuilder, error) {
+	switch op {
+	case "":
+		return cond.Eq(parseValue(raw)), nil
+	case "ne":
+		return cond.Ne(parseValue(raw)), nil
+	case "lt":
+		return cond.Lt(parseValue(raw)), nil
+	case "gt":
+		return cond.Gt(parseValue(raw)), nil
+	case "lte":
+		return cond.Lte(parseValue(raw)), nil
+	case "gte":
+		return cond.Gte(parseValue(raw)), nil
+	case "pfx":
+		// prefixes are always strings
+		return cond.Pfx(raw), nil
+	case "r":
+		r, ok := parseValue(raw).([]interface{})
+		if !ok || len(r) != 2 {
+			return nil, fmt.Errorf("%w: range '%s' is not a list of two values", deta.ErrBadQuery, raw)
+		}
+		return cond.Range(r[0], r[1]), nil
+	case "contains":
+		return cond.Contains(parseValue(raw)), nil
+	case "not_contains":
+		return cond.NotContains(parseValue(raw)), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operator '%s'", deta.ErrBadQuery, op)
+	}
+}
@@ -1,13 +1,26 @@
 package deta
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 // Deta is the top-level Deta service instance
 type Deta struct {
-	ProjectKey string // deta project key
+	ProjectKey  string       // deta project key
+	HTTPClient  *http.Client // http client used by the service clients
+	RetryPolicy *RetryPolicy // retry policy for failed requests, nil disables retries
+
+	BaseRootEndpoint  string // root endpoint of the Base API, overrides DETA_BASE_ROOT_ENDPOINT
+	DriveRootEndpoint string // root endpoint of the Drive API, overrides DETA_DRIVE_ROOT_ENDPOINT
+
+	timeout   time.Duration
+	transport http.RoundTripper
 }
 
 // ConfigOption is a functional config option for Deta
@@ -20,6 +33,47 @@ func WithProjectKey(projectKey string) ConfigOption {
 	}
 }
 
+// WithBaseRootEndpoint config option for setting the root endpoint of the Base API
+func WithBaseRootEndpoint(endpoint string) ConfigOption {
+	return func(d *Deta) {
+		d.BaseRootEndpoint = endpoint
+	}
+}
+
+// WithDriveRootEndpoint config option for setting the root endpoint of the Drive API
+func WithDriveRootEndpoint(endpoint string) ConfigOption {
+	return func(d *Deta) {
+		d.DriveRootEndpoint = endpoint
+	}
+}
+
+// WithHTTPClient config option for setting the http client used for requests to Deta
+//
+// The client can be shared between service instances to share its connection pool.
+func WithHTTPClient(client *http.Client) ConfigOption {
+	return func(d *Deta) {
+		d.HTTPClient = client
+	}
+}
+
+// WithTimeout config option for setting the timeout of each request to Deta
+//
+// The timeout covers sending the request and receiving the response headers. Reading the body
+// of a response, like the content of a file downloaded from a Drive, is not limited by it, use
+// the context of the request to limit it.
+func WithTimeout(timeout time.Duration) ConfigOption {
+	return func(d *Deta) {
+		d.timeout = timeout
+	}
+}
+
+// WithTransport config option for setting the transport used for requests to Deta
+func WithTransport(transport http.RoundTripper) ConfigOption {
+	return func(d *Deta) {
+		d.transport = transport
+	}
+}
+
 // New returns a pointer to a new Deta instance
 func New(opts ...ConfigOption) (*Deta, error) {
 	d := &Deta{
@@ -32,5 +86,76 @@ func New(opts ...ConfigOption) (*Deta, error) {
 	if len(strings.Split(d.ProjectKey, "_")) != 2 {
 		return nil, ErrBadProjectKey
 	}
+	d.HTTPClient = d.httpClient()
 	return d, nil
 }
+
+// returns the http client with the configured timeout and transport applied
+func (d *Deta) httpClient() *http.Client {
+	if d.timeout == 0 && d.transport == nil {
+		if d.HTTPClient == nil {
+			return &http.Client{}
+		}
+		return d.HTTPClient
+	}
+
+	// copy the provided client so that it is not modified
+	c := &http.Client{}
+	if d.HTTPClient != nil {
+		*c = *d.HTTPClient
+	}
+	if d.transport != nil {
+		c.Transport = d.transport
+	}
+	if d.timeout != 0 {
+		c.Transport = &timeoutTransport{base: c.Transport, timeout: d.timeout}
+	}
+	return c
+}
+
+// an http.RoundTripper cancelling requests without response headers after a timeout
+type timeoutTransport struct {
+	// nil uses http.DefaultTransport
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	timer := time.AfterFunc(t.timeout, cancel)
+	res, err := base.RoundTrip(r.WithContext(ctx))
+	if !timer.Stop() {
+		// the timeout passed before the response headers
+		if res != nil {
+			res.Body.Close()
+		}
+		cancel()
+		if r.Context().Err() == nil {
+			err = fmt.Errorf("no response headers after %v: %w", t.timeout, context.DeadlineExceeded)
+		}
+		return nil, err
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the request is cancelled once its body is closed
+	res.Body = &cancelReadCloser{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// an io.ReadCloser calling cancel once it is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (rc *cancelReadCloser) Close() error {
+	err := rc.ReadCloser.Close()
+	rc.cancel()
+	return err
+}
@@ -0,0 +1,132 @@
+package deta
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		projectKey string
+		err        error
+	}{
+		{"project_key", nil},
+		{"", ErrBadProjectKey},
+		{"projectkey", ErrBadProjectKey},
+		{"project_key_extra", ErrBadProjectKey},
+	}
+
+	for _, tc := range testCases {
+		_, err := New(WithProjectKey(tc.projectKey))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Unexpected error value for project key %s. Expected: %v Got: %v", tc.projectKey, tc.err, err)
+		}
+	}
+}
+
+func TestHTTPClientOptions(t *testing.T) {
+	d, err := New(WithProjectKey("project_key"))
+	if err != nil {
+		t.Fatalf("Failed to create deta instance with error %v", err)
+	}
+	if d.HTTPClient == nil {
+		t.Errorf("Expected a default http client")
+	}
+
+	client := &http.Client{}
+	d, err = New(WithProjectKey("project_key"), WithHTTPClient(client))
+	if err != nil {
+		t.Fatalf("Failed to create deta instance with error %v", err)
+	}
+	if d.HTTPClient != client {
+		t.Errorf("Expected the provided http client to be used")
+	}
+
+	transport := &http.Transport{}
+	d, err = New(
+		WithProjectKey("project_key"),
+		WithHTTPClient(client),
+		WithTimeout(time.Second),
+		WithTransport(transport),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create deta instance with error %v", err)
+	}
+	if d.HTTPClient == client {
+		t.Errorf("Expected the provided http client to not be modified")
+	}
+	tt, ok := d.HTTPClient.Transport.(*timeoutTransport)
+	if !ok || tt.timeout != time.Second {
+		t.Fatalf("Unexpected transport. Expected a timeout of %v Got: %#v", time.Second, d.HTTPClient.Transport)
+	}
+	if tt.base != transport {
+		t.Errorf("Expected the provided transport to be used")
+	}
+	if client.Timeout != 0 || client.Transport != nil {
+		t.Errorf("Expected the provided http client to not be modified")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow_headers" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("con"))
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("tent"))
+	}))
+	defer srv.Close()
+
+	d, err := New(WithProjectKey("project_key"), WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create deta instance with error %v", err)
+	}
+
+	// the body is read after the timeout
+	res, err := d.HTTPClient.Get(srv.URL + "/slow_body")
+	if err != nil {
+		t.Fatalf("Failed to get response with error %v", err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || string(body) != "content" {
+		t.Errorf("Unexpected body. Expected: %q Got: %q (error: %v)", "content", body, err)
+	}
+
+	if _, err := d.HTTPClient.Get(srv.URL + "/slow_headers"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+		Multiplier: 2,
+	}
+
+	testCases := []struct {
+		attempt int
+		backoff time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+
+	for _, tc := range testCases {
+		if b := p.Backoff(tc.attempt); b != tc.backoff {
+			t.Errorf("Unexpected backoff for attempt %d. Expected: %v Got: %v", tc.attempt, tc.backoff, b)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package detatest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/deta/deta-go/internal/query"
+)
+
+const (
+	// maximum number of items in a put request
+	maxPutItems = 25
+	// default maximum number of items in a query response
+	defaultQueryLimit = 1000
+	// name of the field storing the expiration timestamp of an item
+	expiresField = "__expires"
+)
+
+// an in-memory base
+type fakeBase struct {
+	items map[string]map[string]interface{}
+}
+
+// serves requests to the base api
+func (s *Server) serveBase(b *fakeBase, w http.ResponseWriter, r *http.Request, rest []string) {
+	switch {
+	case len(rest) == 1 && rest[0] == "items" && r.Method == "PUT":
+		s.basePut(b, w, r)
+	case len(rest) == 1 && rest[0] == "items" && r.Method == "POST":
+		s.baseInsert(b, w, r)
+	case len(rest) == 2 && rest[0] == "items" && r.Method == "GET":
+		s.baseGet(b, w, rest[1])
+	case len(rest) == 2 && rest[0] == "items" && r.Method == "PATCH":
+		s.baseUpdate(b, w, r, rest[1])
+	case len(rest) == 2 && rest[0] == "items" && r.Method == "DELETE":
+		s.baseDelete(b, w, rest[1])
+	case len(rest) == 1 && rest[0] == "query" && r.Method == "POST":
+		s.baseQuery(b, w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// returns the item with the key if it exists and has not expired
+func (s *Server) baseItem(b *fakeBase, key string) (map[string]interface{}, bool) {
+	item, ok := b.items[key]
+	if !ok {
+		return nil, false
+	}
+	if expires, ok := item[expiresField].(float64); ok && expires <= float64(s.now().Unix()) {
+		delete(b.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+// validates the item and sets a key if the item has none
+func (s *Server) prepareItem(item map[string]interface{}) error {
+	if _, ok := item["key"]; !ok {
+		item["key"] = s.newKey()
+	}
+	if k, ok := item["key"].(string); !ok || k == "" {
+		return fmt.Errorf("key must be a non-empty string")
+	}
+	if _, ok := item[expiresField]; ok {
+		if _, ok := item[expiresField].(float64); !ok {
+			return fmt.Errorf("%s must be a number", expiresField)
+		}
+	}
+	return nil
+}
+
+type putRequest struct {
+	Items []map[string]interface{} `json:"items"`
+}
+
+func (s *Server) basePut(b *fakeBase, w http.ResponseWriter, r *http.Request) {
+	var req putRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("bad request body: %v", err))
+		return
+	}
+	if len(req.Items) > maxPutItems {
+		writeError(w, http.StatusBadRequest, "too many items")
+		return
+	}
+
+	var errs []string
+	generatedKeys := make([]bool, len(req.Items))
+	for n, item := range req.Items {
+		_, hasKey := item["key"]
+		generatedKeys[n] = !hasKey
+		if err := s.prepareItem(item); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	processed := make([]map[string]interface{}, 0, len(req.Items))
+	failed := make([]map[string]interface{}, 0)
+	for n, item := range req.Items {
+		if s.FailPutItem != nil && s.FailPutItem(item) {
+			// failed items are returned as they were sent
+			if generatedKeys[n] {
+				delete(item, "key")
+			}
+			failed = append(failed, item)
+			continue
+		}
+		b.items[item["key"].(string)] = item
+		processed = append(processed, item)
+	}
+
+	res := map[string]interface{}{
+		"processed": map[string]interface{}{
+			"items": processed,
+		},
+	}
+	if len(failed) > 0 {
+		res["failed"] = map[string]interface{}{
+			"items": failed,
+		}
+	}
+	writeJSON(w, http.StatusMultiStatus, res)
+}
+
+type insertRequest struct {
+	Item map[string]interface{} `json:"item"`
+}
+
+func (s *Server) baseInsert(b *fakeBase, w http.ResponseWriter, r *http.Request) {
+	var req insertRequest
+	if err := decodeBody(r, &req); err != nil || req.Item == nil {
+		writeError(w, http.StatusBadRequest, "bad request body")
+		return
+	}
+	if err := s.prepareItem(req.Item); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := req.Item["key"].(string)
+	if _, ok := s.baseItem(b, key); ok {
+		writeError(w, http.StatusConflict, fmt.Sprintf("item with key '%s' already exists", key))
+		return
+	}
+	b.items[key] = req.Item
+	writeJSON(w, http.StatusCreated, req.Item)
+}
+
+func (s *Server) baseGet(b *fakeBase, w http.ResponseWriter, key string) {
+	item, ok := s.baseItem(b, key)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"key": key})
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+type updateRequest struct {
+	Set       map[string]interface{} `json:"set"`
+	Delete    []string               `json:"delete"`
+	Append    map[string]interface{} `json:"append"`
+	Prepend   map[string]interface{} `json:"prepend"`
+	Increment map[string]interface{} `json:"increment"`
+}
+
+func (s *Server) baseUpdate(b *fakeBase, w http.ResponseWriter, r *http.Request, key string) {
+	var req updateRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("bad request body: %v", err))
+		return
+	}
+	item, ok := s.baseItem(b, key)
+	if !ok {
+		writeError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	if _, ok := req.Set["key"]; ok {
+		writeError(w, http.StatusBadRequest, "cannot update the key")
+		return
+	}
+
+	// apply the updates on a copy so that a failed update leaves the item unchanged
+	updated := copyValue(item).(map[string]interface{})
+	var errs []string
+	for field, value := range req.Set {
+		setField(updated, field, value)
+	}
+	for field, value := range req.Increment {
+		if err := incrementField(updated, field, value); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for field, value := range req.Append {
+		if err := extendField(updated, field, value, false); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for field, value := range req.Prepend {
+		if err := extendField(updated, field, value, true); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, field := range req.Delete {
+		deleteField(updated, field)
+	}
+	if len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, errs...)
+		return
+	}
+
+	b.items[key] = updated
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"key":       key,
+		"set":       req.Set,
+		"delete":    req.Delete,
+		"append":    req.Append,
+		"prepend":   req.Prepend,
+		"increment": req.Increment,
+	})
+}
+
+func (s *Server) baseDelete(b *fakeBase, w http.ResponseWriter, key string) {
+	delete(b.items, key)
+	writeJSON(w, http.StatusOK, map[string]string{"key": key})
+}
+
+type queryRequest struct {
+	Query []map[string]interface{} `json:"query"`
+	Limit *int                     `json:"limit"`
+	Last  *string                  `json:"last"`
+	Sort  *string                  `json:"sort"`
+}
+
+func (s *Server) baseQuery(b *fakeBase, w http.ResponseWriter, r *http.Request) {
+	var req queryRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("bad request body: %v", err))
+		return
+	}
+	if err := query.Validate(req.Query); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	desc := req.Sort != nil && *req.Sort == "desc"
+	limit := defaultQueryLimit
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+
+	keys := make([]string, 0, len(b.items))
+	for key := range b.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if desc {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+
+	items := make([]map[string]interface{}, 0)
+	var last *string
+	for _, key := range keys {
+		if req.Last != nil {
+			if !desc && strings.Compare(key, *req.Last) <= 0 {
+				continue
+			}
+			if desc && strings.Compare(key, *req.Last) >= 0 {
+				continue
+			}
+		}
+		item, ok := s.baseItem(b, key)
+		if !ok || !query.Match(req.Query, item) {
+			continue
+		}
+		if len(items) == limit {
+			k := items[len(items)-1]["key"].(string)
+			last = &k
+			break
+		}
+		items = append(items, item)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"paging": map[string]interface{}{
+			"size": len(items),
+			"last": last,
+		},
+		"items": items,
+	})
+}
@@ -0,0 +1,200 @@
+// Package detatest provides an in-memory fake of the Deta Base and Drive HTTP APIs for tests.
+//
+// The fake server runs in process on a local address, so tests do not need a real project key
+// or network access.
+//
+//	srv := detatest.NewServer()
+//	defer srv.Close()
+//
+//	// Create a new Deta instance talking to the fake server
+//	d, err := srv.Deta()
+//	if err != nil {
+//		t.Fatalf("failed to create new deta instance: %v", err)
+//	}
+//	users, err := base.New(d, "users")
+//
+// The server can also be used through the DETA_BASE_ROOT_ENDPOINT and DETA_DRIVE_ROOT_ENDPOINT
+// environment variables.
+//
+//	os.Setenv("DETA_BASE_ROOT_ENDPOINT", srv.BaseEndpoint())
+//	os.Setenv("DETA_DRIVE_ROOT_ENDPOINT", srv.DriveEndpoint())
+package detatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+)
+
+const (
+	// ProjectKey a project key accepted by the fake server
+	ProjectKey = "detatest_projectkey"
+
+	basePrefix  = "/base"
+	drivePrefix = "/drive"
+)
+
+// Server is an in-memory fake Deta server
+type Server struct {
+	*httptest.Server
+
+	// Now returns the current time used to expire items, time.Now if nil
+	Now func() time.Time
+
+	// FailPutItem reports whether an item of a put request fails, to simulate failed items
+	FailPutItem func(item map[string]interface{}) bool
+
+	// FailUploadPart reports whether a part of a chunked upload fails, to simulate failed parts
+	FailUploadPart func(name string, part int) bool
+
+	mu     sync.Mutex
+	bases  map[string]*fakeBase
+	drives map[string]*fakeDrive
+	rnd    *rand.Rand
+}
+
+// NewServer starts and returns a new fake Deta server
+//
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		bases:  make(map[string]*fakeBase),
+		drives: make(map[string]*fakeDrive),
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseEndpoint returns the root endpoint of the fake Base API
+func (s *Server) BaseEndpoint() string {
+	return s.URL + basePrefix
+}
+
+// DriveEndpoint returns the root endpoint of the fake Drive API
+func (s *Server) DriveEndpoint() string {
+	return s.URL + drivePrefix
+}
+
+// Deta returns a pointer to a new Deta instance configured to use the fake server
+//
+// ProjectKey is used as the project key unless another one is provided in the options.
+func (s *Server) Deta(opts ...deta.ConfigOption) (*deta.Deta, error) {
+	opts = append([]deta.ConfigOption{
+		deta.WithProjectKey(ProjectKey),
+		deta.WithBaseRootEndpoint(s.BaseEndpoint()),
+		deta.WithDriveRootEndpoint(s.DriveEndpoint()),
+	}, opts...)
+	return deta.New(opts...)
+}
+
+// returns the current time
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+const keyChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// returns a new random key, the caller must hold s.mu
+func (s *Server) newKey() string {
+	b := make([]byte, 12)
+	for i := range b {
+		b[i] = keyChars[s.rnd.Intn(len(keyChars))]
+	}
+	return string(b)
+}
+
+// routes requests to the base and drive handlers
+//
+// Paths are of the form /{service}/{project_id}/{name}/{rest}.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if len(segments) < 4 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	for n, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad path")
+			return
+		}
+		segments[n] = unescaped
+	}
+
+	service, projectID, name, rest := segments[0], segments[1], segments[2], segments[3:]
+	key := r.Header.Get("X-API-Key")
+	if key == "" || strings.Split(key, "_")[0] != projectID {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// the request body is read and the response is buffered, so that the lock is only held
+	// while the state is changed and not while the client sends or reads
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad body")
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.mu.Lock()
+	id := projectID + "/" + name
+	switch "/" + service {
+	case basePrefix:
+		b, ok := s.bases[id]
+		if !ok {
+			b = &fakeBase{items: make(map[string]map[string]interface{})}
+			s.bases[id] = b
+		}
+		s.serveBase(b, rec, r, rest)
+	case drivePrefix:
+		d, ok := s.drives[id]
+		if !ok {
+			d = &fakeDrive{
+				files:   make(map[string]*fakeFile),
+				uploads: make(map[string]*fakeUpload),
+			}
+			s.drives[id] = d
+		}
+		s.serveDrive(d, projectID, name, rec, r, rest)
+	default:
+		writeError(rec, http.StatusNotFound, "not found")
+	}
+	s.mu.Unlock()
+
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rec.Code)
+	rec.Body.WriteTo(w)
+}
+
+// writes a json response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writes a json error response
+func writeError(w http.ResponseWriter, status int, errs ...string) {
+	writeJSON(w, status, map[string][]string{"errors": errs})
+}
+
+// decodes the json request body into v
+func decodeBody(r *http.Request, v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
@@ -0,0 +1,141 @@
+package detatest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
+	"github.com/deta/deta-go/service/base"
+	"github.com/deta/deta-go/service/drive"
+)
+
+func TestExpires(t *testing.T) {
+	srv := detatest.NewServer()
+	defer srv.Close()
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv.Now = func() time.Time { return now }
+
+	d, _ := srv.Deta()
+	b, _ := base.New(d, "base")
+
+	_, err := b.Put(map[string]interface{}{
+		"key":       "expiring",
+		"__expires": now.Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to put item with error %v", err)
+	}
+
+	var item map[string]interface{}
+	if err := b.Get("expiring", &item); err != nil {
+		t.Errorf("Failed to get item before expiry with error %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	err = b.Get("expiring", &item)
+	if !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value for expired item. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+}
+
+func TestBadExpires(t *testing.T) {
+	srv := detatest.NewServer()
+	defer srv.Close()
+
+	d, _ := srv.Deta()
+	b, _ := base.New(d, "base")
+
+	// items with and without a key are validated the same way
+	for _, item := range []map[string]interface{}{
+		{"key": "a", "__expires": "tomorrow"},
+		{"__expires": "tomorrow"},
+	} {
+		if _, err := b.Put(item); !errors.Is(err, deta.ErrBadRequest) {
+			t.Errorf("Unexpected error value for %v. Expected: %v Got: %v", item, deta.ErrBadRequest, err)
+		}
+		if _, err := b.Insert(item); !errors.Is(err, deta.ErrBadRequest) {
+			t.Errorf("Unexpected error value for insert of %v. Expected: %v Got: %v", item, deta.ErrBadRequest, err)
+		}
+	}
+}
+
+func TestListLimit(t *testing.T) {
+	srv := detatest.NewServer()
+	defer srv.Close()
+
+	d, _ := srv.Deta()
+	dr, _ := drive.New(d, "drive")
+
+	for _, limit := range []int{1, 1000} {
+		if _, err := dr.List(limit, "", ""); err != nil {
+			t.Errorf("Failed to list with limit %d with error %v", limit, err)
+		}
+	}
+	for _, limit := range []int{-1, 0, 1001} {
+		if _, err := dr.List(limit, "", ""); !errors.Is(err, deta.ErrBadRequest) {
+			t.Errorf("Unexpected error value for limit %d. Expected: %v Got: %v", limit, deta.ErrBadRequest, err)
+		}
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	srv := detatest.NewServer()
+	defer srv.Close()
+
+	d, _ := srv.Deta()
+	dr, _ := drive.New(d, "drive")
+
+	uploadsURL := srv.DriveEndpoint() + "/detatest/drive/uploads"
+	req, _ := http.NewRequest("POST", uploadsURL+"?name=slow.txt", nil)
+	req.Header.Set("X-API-Key", detatest.ProjectKey)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to start upload with error %v", err)
+	}
+	var session struct {
+		UploadID string `json:"upload_id"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&session)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode upload with error %v", err)
+	}
+
+	// a part whose body is still being sent does not block other requests
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	partURL := uploadsURL + "/" + session.UploadID + "/parts?name=slow.txt&part=1"
+	req, _ = http.NewRequest("POST", partURL, pr)
+	req.Header.Set("X-API-Key", detatest.ProjectKey)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		res, err := http.DefaultClient.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	pw.Write([]byte("slow"))
+
+	listed := make(chan error, 1)
+	go func() {
+		_, err := dr.List(1000, "", "")
+		listed <- err
+	}()
+	select {
+	case err := <-listed:
+		if err != nil {
+			t.Errorf("Failed to list with error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Request blocked by a request still sending its body")
+	}
+	pw.Close()
+	<-sent
+}
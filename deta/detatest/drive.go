@@ -0,0 +1,255 @@
+package detatest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maximum number of names in a delete request
+	maxDeleteNames = 1000
+	// default and maximum number of names in a list response
+	maxListLimit = 1000
+)
+
+// an in-memory drive
+type fakeDrive struct {
+	files   map[string]*fakeFile
+	uploads map[string]*fakeUpload
+}
+
+// a file stored in a drive
+type fakeFile struct {
+	content     []byte
+	contentType string
+	modTime     time.Time
+	etag        string
+}
+
+// an ongoing chunked upload
+type fakeUpload struct {
+	name        string
+	contentType string
+	parts       map[int][]byte
+}
+
+// serves requests to the drive api
+func (s *Server) serveDrive(d *fakeDrive, projectID, driveName string, w http.ResponseWriter, r *http.Request, rest []string) {
+	switch {
+	case len(rest) == 1 && rest[0] == "files" && r.Method == "GET":
+		s.driveList(d, w, r)
+	case len(rest) == 1 && rest[0] == "files" && r.Method == "DELETE":
+		s.driveDelete(d, w, r)
+	case len(rest) == 2 && rest[0] == "files" && rest[1] == "download" && (r.Method == "GET" || r.Method == "HEAD"):
+		s.driveDownload(d, w, r)
+	case len(rest) == 1 && rest[0] == "uploads" && r.Method == "POST":
+		s.driveStartUpload(d, projectID, driveName, w, r)
+	case len(rest) == 2 && rest[0] == "uploads" && r.Method == "PATCH":
+		s.driveFinishUpload(d, w, r, rest[1])
+	case len(rest) == 2 && rest[0] == "uploads" && r.Method == "DELETE":
+		s.driveAbortUpload(d, w, r, rest[1])
+	case len(rest) == 3 && rest[0] == "uploads" && rest[2] == "parts" && r.Method == "POST":
+		s.driveUploadPart(d, w, r, rest[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) driveList(d *fakeDrive, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := maxListLimit
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxListLimit {
+			writeError(w, http.StatusBadRequest, "bad limit")
+			return
+		}
+		limit = n
+	}
+	prefix, last := q.Get("prefix"), q.Get("last")
+
+	names := make([]string, 0, len(d.files))
+	for name := range d.files {
+		if strings.HasPrefix(name, prefix) && (last == "" || name > last) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	paging := map[string]interface{}{}
+	if len(names) > limit {
+		names = names[:limit]
+		paging["last"] = names[limit-1]
+	}
+	paging["size"] = len(names)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"paging": paging,
+		"names":  names,
+	})
+}
+
+type deleteRequest struct {
+	Names []string `json:"names"`
+}
+
+func (s *Server) driveDelete(d *fakeDrive, w http.ResponseWriter, r *http.Request) {
+	var req deleteRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("bad request body: %v", err))
+		return
+	}
+	if len(req.Names) == 0 {
+		writeError(w, http.StatusBadRequest, "no names provided")
+		return
+	}
+	if len(req.Names) > maxDeleteNames {
+		writeError(w, http.StatusBadRequest, "too many names")
+		return
+	}
+	for _, name := range req.Names {
+		delete(d.files, name)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deleted": req.Names,
+		"failed":  map[string]string{},
+	})
+}
+
+func (s *Server) driveDownload(d *fakeDrive, w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	f, ok := d.files[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("ETag", f.etag)
+	http.ServeContent(w, r, name, f.modTime, bytes.NewReader(f.content))
+}
+
+func (s *Server) driveStartUpload(d *fakeDrive, projectID, driveName string, w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "no name provided")
+		return
+	}
+	uploadID := s.newKey()
+	d.uploads[uploadID] = &fakeUpload{
+		name:  name,
+		parts: make(map[int][]byte),
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"upload_id":  uploadID,
+		"name":       name,
+		"project_id": projectID,
+		"drive_name": driveName,
+	})
+}
+
+// returns the upload with the id if the name of the request matches
+func (s *Server) driveUpload(d *fakeDrive, w http.ResponseWriter, r *http.Request, uploadID string) (*fakeUpload, bool) {
+	u, ok := d.uploads[uploadID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "upload not found")
+		return nil, false
+	}
+	if r.URL.Query().Get("name") != u.name {
+		writeError(w, http.StatusBadRequest, "name does not match the upload")
+		return nil, false
+	}
+	return u, true
+}
+
+func (s *Server) driveUploadPart(d *fakeDrive, w http.ResponseWriter, r *http.Request, uploadID string) {
+	u, ok := s.driveUpload(d, w, r, uploadID)
+	if !ok {
+		return
+	}
+	part, err := strconv.Atoi(r.URL.Query().Get("part"))
+	if err != nil || part < 1 {
+		writeError(w, http.StatusBadRequest, "bad part number")
+		return
+	}
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read part")
+		return
+	}
+	if s.FailUploadPart != nil && s.FailUploadPart(u.name, part) {
+		writeError(w, http.StatusInternalServerError, "failed to upload part")
+		return
+	}
+	u.parts[part] = content
+	if part == 1 || u.contentType == "" {
+		u.contentType = r.Header.Get("Content-Type")
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":      u.name,
+		"upload_id": uploadID,
+		"part":      part,
+	})
+}
+
+func (s *Server) driveFinishUpload(d *fakeDrive, w http.ResponseWriter, r *http.Request, uploadID string) {
+	u, ok := s.driveUpload(d, w, r, uploadID)
+	if !ok {
+		return
+	}
+	if len(u.parts) == 0 {
+		writeError(w, http.StatusBadRequest, "no parts uploaded")
+		return
+	}
+
+	numbers := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	var content, partHashes []byte
+	for _, n := range numbers {
+		content = append(content, u.parts[n]...)
+		h := md5.Sum(u.parts[n])
+		partHashes = append(partHashes, h[:]...)
+	}
+	// the etag of a single part file is the md5 hash of its content, multipart files have
+	// the hash of the hashes of their parts followed by the number of parts
+	etag := fmt.Sprintf(`"%x"`, md5.Sum(content))
+	if len(numbers) > 1 {
+		etag = fmt.Sprintf(`"%x-%d"`, md5.Sum(partHashes), len(numbers))
+	}
+
+	contentType := u.contentType
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	d.files[u.name] = &fakeFile{
+		content:     content,
+		contentType: contentType,
+		modTime:     s.now(),
+		etag:        etag,
+	}
+	delete(d.uploads, uploadID)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":      u.name,
+		"upload_id": uploadID,
+	})
+}
+
+func (s *Server) driveAbortUpload(d *fakeDrive, w http.ResponseWriter, r *http.Request, uploadID string) {
+	u, ok := s.driveUpload(d, w, r, uploadID)
+	if !ok {
+		return
+	}
+	delete(d.uploads, uploadID)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":      u.name,
+		"upload_id": uploadID,
+	})
+}
@@ -0,0 +1,104 @@
+package detatest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deta/deta-go/internal/query"
+)
+
+// returns a deep copy of a json decoded value
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[k] = copyValue(v)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for n, v := range val {
+			l[n] = copyValue(v)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// returns the map holding the field and the last part of the field
+//
+// Missing parent maps are created if create is true.
+func parentOf(item map[string]interface{}, field string, create bool) (map[string]interface{}, string) {
+	parts := strings.Split(field, ".")
+	cur := item
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := cur[part].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = make(map[string]interface{})
+			cur[part] = next
+		}
+		cur = next
+	}
+	return cur, parts[len(parts)-1]
+}
+
+// sets the value of a field
+func setField(item map[string]interface{}, field string, value interface{}) {
+	parent, last := parentOf(item, field, true)
+	parent[last] = value
+}
+
+// deletes a field, deleting a missing field is a no-op
+func deleteField(item map[string]interface{}, field string) {
+	parent, last := parentOf(item, field, false)
+	if parent != nil {
+		delete(parent, last)
+	}
+}
+
+// increments a numeric field by value
+func incrementField(item map[string]interface{}, field string, value interface{}) error {
+	inc, ok := value.(float64)
+	if !ok {
+		return fmt.Errorf("increment value of '%s' is not a number", field)
+	}
+	cur, ok := query.Field(item, field)
+	if !ok {
+		setField(item, field, inc)
+		return nil
+	}
+	n, ok := cur.(float64)
+	if !ok {
+		return fmt.Errorf("field '%s' is not a number", field)
+	}
+	setField(item, field, n+inc)
+	return nil
+}
+
+// appends or prepends values to a list field
+func extendField(item map[string]interface{}, field string, value interface{}, prepend bool) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	cur, ok := query.Field(item, field)
+	if !ok {
+		setField(item, field, values)
+		return nil
+	}
+	l, ok := cur.([]interface{})
+	if !ok {
+		return fmt.Errorf("field '%s' is not a list", field)
+	}
+	if prepend {
+		setField(item, field, append(append([]interface{}{}, values...), l...))
+	} else {
+		setField(item, field, append(append([]interface{}{}, l...), values...))
+	}
+	return nil
+}
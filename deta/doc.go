@@ -20,5 +20,45 @@ You can use the WithProjectKey option when creating a Deta instance to provide t
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create new deta instance: %v\n", err)
 	}
+
+Configuring the HTTP client
+
+The service clients created from a Deta instance share its HTTP client.
+Use the WithHTTPClient, WithTimeout and WithTransport options to configure it.
+
+	// Create a new Deta instance with a request timeout and a custom transport
+	d, err := deta.New(
+		deta.WithTimeout(10*time.Second),
+		deta.WithTransport(&http.Transport{Proxy: http.ProxyFromEnvironment}),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create new deta instance: %v\n", err)
+	}
+
+Retrying failed requests
+
+Requests are not retried by default. Use the WithRetryPolicy option to retry requests that fail
+with network errors or retryable status codes, with exponential backoff and jitter.
+Only idempotent operations are retried unless RetryNonIdempotent is set in the policy.
+
+	// Create a new Deta instance that retries failed requests
+	d, err := deta.New(deta.WithRetryPolicy(deta.DefaultRetryPolicy()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create new deta instance: %v\n", err)
+	}
+
+Errors
+
+Error responses from Deta are returned as an *APIError carrying the status code, all error messages,
+the response headers and the request path. An APIError matches the exported error of its status code.
+
+	err := users.Get("jimmy_neutron", &u)
+	if errors.Is(err, deta.ErrNotFound) {
+		fmt.Println("user not found")
+	}
+	var apiErr *deta.APIError
+	if errors.As(err, &apiErr) {
+		fmt.Println(apiErr.StatusCode, apiErr.Errors)
+	}
 */
 package deta
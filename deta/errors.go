@@ -2,12 +2,15 @@ package deta
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 )
 
 var (
 	// ErrBadProjectKey bad project key
 	ErrBadProjectKey = errors.New("bad project key")
-	
+
 	// ErrBadRequest bad request
 	ErrBadRequest = errors.New("bad request")
 	// ErrUnauthorized aunauthorized
@@ -16,6 +19,12 @@ var (
 	ErrNotFound = errors.New("not found")
 	// ErrConflict conflict
 	ErrConflict = errors.New("conflict")
+	// ErrPayloadTooLarge payload too large
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrRateLimited rate limited
+	ErrRateLimited = errors.New("rate limited")
+	// ErrRangeNotSatisfiable range not satisfiable
+	ErrRangeNotSatisfiable = errors.New("range not satisfiable")
 	// ErrInternalServerError internal server error
 	ErrInternalServerError = errors.New("internal server error")
 
@@ -30,6 +39,12 @@ var (
 	ErrBadDestination = errors.New("bad destination")
 	// ErrBadItem bad item/items
 	ErrBadItem = errors.New("bad item/items")
+	// ErrItemsFailed items failed to be processed
+	ErrItemsFailed = errors.New("items failed to be processed")
+	// ErrBadQuery bad query
+	ErrBadQuery = errors.New("bad query")
+	// ErrBadFormat bad format
+	ErrBadFormat = errors.New("bad format")
 
 	// ErrBadDriveName bad drive name
 	ErrBadDriveName = errors.New("bad drive name")
@@ -38,7 +53,78 @@ var (
 	// ErrEmptyNames empty names
 	ErrEmptyNames = errors.New("names is empty")
 	// ErrTooManyNames too many names
+	//
+	// Deprecated: DeleteMany splits the names into requests of at most 1000 names and
+	// no longer returns it.
 	ErrTooManyNames = errors.New("too many names")
 	// ErrEmptyData no data
 	ErrEmptyData = errors.New("no data provided")
+	// ErrBadUploadSession bad upload session
+	ErrBadUploadSession = errors.New("bad upload session")
+	// ErrBadOffset bad offset
+	ErrBadOffset = errors.New("bad offset")
+	// ErrBadSyncInput bad sync input
+	ErrBadSyncInput = errors.New("bad sync input")
+	// ErrUnsafeName name of a file in the Drive that escapes the local directory
+	ErrUnsafeName = errors.New("unsafe name")
+	// ErrBadCopyInput bad copy input
+	ErrBadCopyInput = errors.New("bad copy input")
+
+	// ErrBadSnapshot bad snapshot
+	ErrBadSnapshot = errors.New("bad snapshot")
 )
+
+// APIError is an error response from a Deta API
+//
+// An APIError matches the error of its status code with errors.Is:
+//
+//	if errors.Is(err, deta.ErrNotFound) {
+//		// item not found
+//	}
+//
+// Status codes without a specific error match ErrInternalServerError.
+type APIError struct {
+	StatusCode int         // response status code
+	Errors     []string    // error messages from the response
+	Header     http.Header // response headers
+	Path       string      // request path
+}
+
+// returns the error of the status code
+func (e *APIError) statusErr() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	case http.StatusRequestedRangeNotSatisfiable:
+		return ErrRangeNotSatisfiable
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return ErrInternalServerError
+	}
+}
+
+func (e *APIError) Error() string {
+	statusErr := e.statusErr()
+	msg := statusErr.Error()
+	if statusErr == ErrInternalServerError && e.StatusCode != http.StatusInternalServerError {
+		msg = fmt.Sprintf("%s (status %d)", msg, e.StatusCode)
+	}
+	if len(e.Errors) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(e.Errors, "; "))
+	}
+	return msg
+}
+
+// Unwrap returns the error of the status code
+func (e *APIError) Unwrap() error {
+	return e.statusErr()
+}
@@ -0,0 +1,69 @@
+package deta
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	testCases := []struct {
+		err *APIError
+		is  error
+		msg string
+	}{
+		{
+			err: &APIError{StatusCode: 400, Errors: []string{"bad key", "bad value"}},
+			is:  ErrBadRequest,
+			msg: "bad request: bad key; bad value",
+		},
+		{
+			err: &APIError{StatusCode: 401, Errors: []string{"invalid api key"}},
+			is:  ErrUnauthorized,
+			msg: "unauthorized: invalid api key",
+		},
+		{
+			err: &APIError{StatusCode: 404},
+			is:  ErrNotFound,
+			msg: "not found",
+		},
+		{
+			err: &APIError{StatusCode: 409, Errors: []string{"key already exists"}},
+			is:  ErrConflict,
+			msg: "conflict: key already exists",
+		},
+		{
+			err: &APIError{StatusCode: 413},
+			is:  ErrPayloadTooLarge,
+			msg: "payload too large",
+		},
+		{
+			err: &APIError{StatusCode: 416},
+			is:  ErrRangeNotSatisfiable,
+			msg: "range not satisfiable",
+		},
+		{
+			err: &APIError{StatusCode: 429},
+			is:  ErrRateLimited,
+			msg: "rate limited",
+		},
+		{
+			err: &APIError{StatusCode: 500},
+			is:  ErrInternalServerError,
+			msg: "internal server error",
+		},
+		{
+			err: &APIError{StatusCode: 503, Errors: []string{"unavailable"}},
+			is:  ErrInternalServerError,
+			msg: "internal server error (status 503): unavailable",
+		},
+	}
+
+	for _, tc := range testCases {
+		if !errors.Is(tc.err, tc.is) {
+			t.Errorf("Expected error with status %d to be %v", tc.err.StatusCode, tc.is)
+		}
+		if tc.err.Error() != tc.msg {
+			t.Errorf("Unexpected error message. Expected: %s Got: %s", tc.msg, tc.err.Error())
+		}
+	}
+}
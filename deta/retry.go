@@ -0,0 +1,93 @@
+package deta
+
+import (
+	"net/http"
+	"time"
+)
+
+// RetryPolicy configures how failed requests to Deta are retried
+//
+// Requests are retried on network errors and on responses with a retryable status code.
+// Only idempotent operations are retried unless RetryNonIdempotent is set. A request is not
+// retried if the delay before the retry ends after the deadline of its context.
+type RetryPolicy struct {
+	// maximum number of attempts of a request, including the first attempt
+	// value of 1 or less disables retries
+	MaxAttempts int
+	// delay before the first retry
+	MinBackoff time.Duration
+	// maximum delay between retries
+	// a request is not retried if a Retry-After header requests a longer delay
+	// value of 0 or less applies no maximum
+	MaxBackoff time.Duration
+	// factor by which the delay grows after every retry
+	// value less than 1 keeps the delay constant
+	Multiplier float64
+	// fraction of the delay, from 0 to 1, that is randomly subtracted from it
+	Jitter float64
+	// response status codes on which requests are retried
+	// a nil value uses DefaultRetryableStatuses
+	RetryableStatuses []int
+	// also retry non-idempotent operations, Base Insert, Update and puts of items without a key
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryableStatuses status codes retried by default
+var DefaultRetryableStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy returns a pointer to a new RetryPolicy with sensible defaults
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  200 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.5,
+	}
+}
+
+// WithRetryPolicy config option for setting the retry policy for requests to Deta
+//
+// Requests are not retried if no retry policy is set.
+func WithRetryPolicy(p *RetryPolicy) ConfigOption {
+	return func(d *Deta) {
+		d.RetryPolicy = p
+	}
+}
+
+// IsRetryableStatus reports whether a response with the status code should be retried
+func (p *RetryPolicy) IsRetryableStatus(status int) bool {
+	statuses := p.RetryableStatuses
+	if statuses == nil {
+		statuses = DefaultRetryableStatuses
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay, without jitter, before the retry following the attempt
+//
+// Attempts are counted from 1.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.MinBackoff)
+	for n := 1; n < attempt && p.Multiplier > 1; n++ {
+		delay *= p.Multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(delay)
+}
@@ -7,6 +7,8 @@ The SDK consists of two main components, the core package and service packages.
 
 deta - The core SDK package, provides shared functionalities to the service packages. All the errors are also exported from this package.
 
+deta/detatest - An in-memory fake of the Deta Base and Drive APIs for offline tests.
+
 service - The service packages, the services supported by the SDK.
 	base - Deta Base service package
 	drive - Deta Drive service package
@@ -5,12 +5,18 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/deta/deta-go/deta"
 )
@@ -27,20 +33,28 @@ type DetaClient struct {
 	RootEndpoint string
 	Client       *http.Client
 	AuthInfo     *AuthInfo
+	RetryPolicy  *deta.RetryPolicy
 }
 
 // NewDetaClient returns a pointer to a new deta client
-func NewDetaClient(rootEndpoint string, ai *AuthInfo) *DetaClient {
+//
+// A default http client is used if httpClient is nil.
+// Requests are not retried if retryPolicy is nil.
+func NewDetaClient(rootEndpoint string, ai *AuthInfo, httpClient *http.Client, retryPolicy *deta.RetryPolicy) *DetaClient {
 	// only api keys auth for now
 	/*
 		if i.Auth.Type != "api-key" {
 			return nil, errInvalidAuthType
 		}
 	*/
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
 	return &DetaClient{
 		RootEndpoint: rootEndpoint,
 		AuthInfo:     ai,
-		Client:       &http.Client{},
+		Client:       httpClient,
+		RetryPolicy:  retryPolicy,
 	}
 }
 
@@ -50,33 +64,37 @@ type errorResp struct {
 	Errors     []string `json:"errors"`
 }
 
-// returns appropriate errors from the error response
-func (c *DetaClient) errorRespToErr(e *errorResp) error {
-	var errorMsg string
-	if len(e.Errors) >= 1 {
-		errorMsg = e.Errors[0]
-	}
-
-	switch e.StatusCode {
-	case 400:
-		return fmt.Errorf("%w: %s", deta.ErrBadRequest, errorMsg)
-	case 401:
-		// does not require wrapping
-		return deta.ErrUnauthorized
-	case 404:
-		// does not require wrapping
-		return deta.ErrNotFound
-	case 409:
-		return fmt.Errorf("%w: %s", deta.ErrConflict, errorMsg)
-	default:
-		// default internal server error for other error status codes
-		// does not require wrapping
-		return deta.ErrInternalServerError
+// maximum length of a non json error response body kept as an error message
+const maxRawErrorLength = 512
+
+// returns the trimmed body as an error message, truncated to maxRawErrorLength bytes
+func rawErrorMessage(b []byte) string {
+	msg := strings.TrimSpace(string(b))
+	if len(msg) <= maxRawErrorLength {
+		return msg
+	}
+	// do not cut a multi-byte character
+	n := maxRawErrorLength
+	for n > 0 && !utf8.RuneStart(msg[n]) {
+		n--
+	}
+	return msg[:n]
+}
+
+// returns an api error from the error response
+func (c *DetaClient) errorRespToErr(e *errorResp, path string, header http.Header) error {
+	return &deta.APIError{
+		StatusCode: e.StatusCode,
+		Errors:     e.Errors,
+		Header:     header,
+		Path:       path,
 	}
 }
 
 // RequestInput to Request method
 type RequestInput struct {
+	// context of the request, context.Background() is used if nil
+	Context          context.Context
 	Path             string
 	Method           string
 	Headers          map[string]string
@@ -85,6 +103,8 @@ type RequestInput struct {
 	RawBody          []byte
 	ContentType      string
 	ReturnReadCloser bool
+	// the request can be safely retried
+	Idempotent bool
 }
 
 // RequestOutput of Request method
@@ -97,6 +117,8 @@ type RequestOutput struct {
 }
 
 // Request constructs and sends the request
+//
+// Failed requests are retried according to the retry policy of the client.
 func (c *DetaClient) Request(i *RequestInput) (*RequestOutput, error) {
 	marshalled := []byte("")
 	if i.Body != nil {
@@ -115,8 +137,42 @@ func (c *DetaClient) Request(i *RequestInput) (*RequestOutput, error) {
 		marshalled = i.RawBody
 	}
 
+	ctx := i.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.send(ctx, i, marshalled)
+
+		delay, retry := c.retryDelay(ctx, i, attempt, res, err)
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
+			return c.output(i, res)
+		}
+
+		if res != nil {
+			// drain the body so that the connection can be reused
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// sends a single attempt of the request
+func (c *DetaClient) send(ctx context.Context, i *RequestInput, body []byte) (*http.Response, error) {
 	url := fmt.Sprintf("%s%s", c.RootEndpoint, i.Path)
-	req, err := http.NewRequest(i.Method, url, bytes.NewBuffer(marshalled))
+	req, err := http.NewRequestWithContext(ctx, i.Method, url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
@@ -143,11 +199,85 @@ func (c *DetaClient) Request(i *RequestInput) (*RequestOutput, error) {
 	req.URL.RawQuery = q.Encode()
 
 	// send the request
-	res, err := c.Client.Do(req)
+	return c.Client.Do(req)
+}
+
+// returns the delay before retrying the request and if the request should be retried
+func (c *DetaClient) retryDelay(ctx context.Context, i *RequestInput, attempt int, res *http.Response, err error) (time.Duration, bool) {
+	p := c.RetryPolicy
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if !i.Idempotent && !p.RetryNonIdempotent {
+		return 0, false
+	}
+
 	if err != nil {
-		return nil, err
+		// do not retry cancelled requests
+		if ctx.Err() != nil {
+			return 0, false
+		}
+	} else if !p.IsRetryableStatus(res.StatusCode) {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if ra, ok := retryAfter(res); ok {
+		// retrying earlier than requested would only be rate limited again
+		if p.MaxBackoff > 0 && ra > p.MaxBackoff {
+			return 0, false
+		}
+		delay = ra
+	} else {
+		delay = p.Backoff(attempt)
+		if p.Jitter > 0 {
+			delay -= time.Duration(p.Jitter * randFloat64() * float64(delay))
+		}
+	}
+
+	// do not wait for a retry that cannot finish before the deadline
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return 0, false
+	}
+	return delay, true
+}
+
+// parses the Retry-After header of the response, either in seconds or a http date
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
 	}
+	if t, err := http.ParseTime(v); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
 
+var (
+	rnd   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rndMu sync.Mutex
+)
+
+// returns a random float64 in [0.0, 1.0)
+func randFloat64() float64 {
+	rndMu.Lock()
+	defer rndMu.Unlock()
+	return rnd.Float64()
+}
+
+// constructs the request output from the response
+func (c *DetaClient) output(i *RequestInput, res *http.Response) (*RequestOutput, error) {
 	// request output
 	o := &RequestOutput{
 		Status: res.StatusCode,
@@ -172,13 +302,15 @@ func (c *DetaClient) Request(i *RequestInput) (*RequestOutput, error) {
 
 	// errors
 	var er errorResp
-	// json unmarshal json error responses
-	if strings.Contains(res.Header.Get("Content-Type"), "application/json") {
-		if err = json.Unmarshal(b, &er); err != nil {
-			return nil, err
+	// json unmarshal json error responses, keep other error bodies as raw messages
+	isJSON := strings.Contains(res.Header.Get("Content-Type"), "application/json")
+	if !isJSON || json.Unmarshal(b, &er) != nil {
+		er = errorResp{}
+		if msg := rawErrorMessage(b); msg != "" {
+			er.Errors = []string{msg}
 		}
 	}
 
 	er.StatusCode = res.StatusCode
-	return nil, c.errorRespToErr(&er)
+	return nil, c.errorRespToErr(&er, i.Path, res.Header)
 }
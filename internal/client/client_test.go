@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deta/deta-go/deta"
+)
+
+func testRetryPolicy() *deta.RetryPolicy {
+	return &deta.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+		Multiplier:  2,
+	}
+}
+
+func TestRequestRetries(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statuses   []int
+		idempotent bool
+		policy     *deta.RetryPolicy
+		attempts   int
+		ok         bool
+	}{
+		{"no retry policy", []int{503, 200}, true, nil, 1, false},
+		{"retried until success", []int{503, 429, 200}, true, testRetryPolicy(), 3, true},
+		{"max attempts reached", []int{503, 503, 503, 200}, true, testRetryPolicy(), 3, false},
+		{"non-retryable status", []int{400, 200}, true, testRetryPolicy(), 1, false},
+		{"non-idempotent request", []int{503, 200}, false, testRetryPolicy(), 1, false},
+		{
+			"non-idempotent request opted in",
+			[]int{503, 200},
+			false,
+			&deta.RetryPolicy{MaxAttempts: 2, RetryNonIdempotent: true},
+			2,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		attempts := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.statuses[attempts])
+			attempts++
+		}))
+
+		c := NewDetaClient(srv.URL, nil, nil, tc.policy)
+		_, err := c.Request(&RequestInput{
+			Path:       "/",
+			Method:     "POST",
+			Body:       map[string]string{"a": "b"},
+			Idempotent: tc.idempotent,
+		})
+		srv.Close()
+
+		if tc.ok != (err == nil) {
+			t.Errorf("%s: unexpected error value %v", tc.name, err)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("%s: unexpected number of attempts. Expected: %d Got: %d", tc.name, tc.attempts, attempts)
+		}
+	}
+}
+
+func TestRequestRetryAfter(t *testing.T) {
+	var first time.Time
+	var delay time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first.IsZero() {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		delay = time.Since(first)
+	}))
+	defer srv.Close()
+
+	p := testRetryPolicy()
+	p.MaxBackoff = 0
+	c := NewDetaClient(srv.URL, nil, nil, p)
+	_, err := c.Request(&RequestInput{
+		Path:       "/",
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed request with error %v", err)
+	}
+	if delay < time.Second {
+		t.Errorf("Retry-After header not honored, retried after %v", delay)
+	}
+}
+
+func TestRequestRetryAfterLimits(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	var apiErr *deta.APIError
+
+	// a delay longer than the maximum backoff is not retried
+	c := NewDetaClient(srv.URL, nil, nil, testRetryPolicy())
+	start := time.Now()
+	_, err := c.Request(&RequestInput{
+		Path:       "/",
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Unexpected error value. Expected status %d Got: %v", http.StatusTooManyRequests, err)
+	}
+	if apiErr != nil && apiErr.Header.Get("Retry-After") != "60" {
+		t.Errorf("Unexpected Retry-After header. Expected: %s Got: %s", "60", apiErr.Header.Get("Retry-After"))
+	}
+	if attempts != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Retry-After above the maximum backoff retried. Expected 1 attempt Got: %d attempts in %v", attempts, time.Since(start))
+	}
+
+	// a retry after the deadline of the context is not waited for
+	attempts = 0
+	p := testRetryPolicy()
+	p.MaxBackoff = 0
+	c = NewDetaClient(srv.URL, nil, nil, p)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start = time.Now()
+	_, err = c.Request(&RequestInput{
+		Context:    ctx,
+		Path:       "/",
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Unexpected error value. Expected status %d Got: %v", http.StatusTooManyRequests, err)
+	}
+	if attempts != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Retry waited past the deadline. Expected 1 attempt Got: %d attempts in %v", attempts, time.Since(start))
+	}
+}
+
+func TestRequestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "request_id")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors": ["first error", "second error"]}`))
+	}))
+	defer srv.Close()
+
+	c := NewDetaClient(srv.URL, nil, nil, nil)
+	_, err := c.Request(&RequestInput{
+		Path:   "/items",
+		Method: "PUT",
+	})
+
+	var apiErr *deta.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Unexpected error value. Expected an api error Got: %v", err)
+	}
+	if !errors.Is(err, deta.ErrBadRequest) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadRequest, err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unexpected status code. Expected: %d Got: %d", http.StatusBadRequest, apiErr.StatusCode)
+	}
+	if !reflect.DeepEqual(apiErr.Errors, []string{"first error", "second error"}) {
+		t.Errorf("Unexpected error messages %v", apiErr.Errors)
+	}
+	if apiErr.Path != "/items" {
+		t.Errorf("Unexpected path. Expected: %s Got: %s", "/items", apiErr.Path)
+	}
+	if apiErr.Header.Get("X-Request-Id") != "request_id" {
+		t.Errorf("Response headers not preserved")
+	}
+}
+
+func TestRequestRawAPIError(t *testing.T) {
+	long := strings.Repeat("a", maxRawErrorLength-1) + "€ and more"
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		errors      []string
+	}{
+		{"plain text", "text/plain", "  bad gateway\n", []string{"bad gateway"}},
+		{"invalid json", "application/json", "<html>bad gateway</html>", []string{"<html>bad gateway</html>"}},
+		{"empty json", "application/json", "", nil},
+		{"truncated", "text/plain", long, []string{long[:maxRawErrorLength-1]}},
+	}
+
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(tc.body))
+		}))
+
+		c := NewDetaClient(srv.URL, nil, nil, &deta.RetryPolicy{MaxAttempts: 1})
+		_, err := c.Request(&RequestInput{
+			Path:   "/items",
+			Method: "GET",
+		})
+		srv.Close()
+
+		var apiErr *deta.APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: Unexpected error value. Expected an api error Got: %v", tc.name, err)
+			continue
+		}
+		if apiErr.StatusCode != http.StatusBadGateway {
+			t.Errorf("%s: Unexpected status code. Expected: %d Got: %d", tc.name, http.StatusBadGateway, apiErr.StatusCode)
+		}
+		if !reflect.DeepEqual(apiErr.Errors, tc.errors) {
+			t.Errorf("%s: Unexpected error messages. Expected: %q Got: %q", tc.name, tc.errors, apiErr.Errors)
+		}
+		for _, msg := range apiErr.Errors {
+			if !utf8.ValidString(msg) {
+				t.Errorf("%s: Error message is not valid utf-8: %q", tc.name, msg)
+			}
+		}
+	}
+}
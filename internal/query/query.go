@@ -0,0 +1,194 @@
+// Package query evaluates Deta Base queries against items locally.
+//
+// Queries and items are expected as decoded from json, with numbers as float64.
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// query operators, used as suffixes of the fields after a '?'
+const (
+	OpEq          = ""
+	OpNe          = "ne"
+	OpLt          = "lt"
+	OpGt          = "gt"
+	OpLte         = "lte"
+	OpGte         = "gte"
+	OpPfx         = "pfx"
+	OpRange       = "r"
+	OpContains    = "contains"
+	OpNotContains = "not_contains"
+)
+
+// SplitKey splits a query key into the field and the operator
+func SplitKey(key string) (string, string) {
+	n := strings.LastIndex(key, "?")
+	if n < 0 {
+		return key, OpEq
+	}
+	return key[:n], key[n+1:]
+}
+
+// Validate validates the operators and operands of a query
+func Validate(q []map[string]interface{}) error {
+	for _, m := range q {
+		for key, operand := range m {
+			field, op := SplitKey(key)
+			if field == "" {
+				return fmt.Errorf("bad query key '%s'", key)
+			}
+			switch op {
+			case OpEq, OpNe, OpLt, OpGt, OpLte, OpGte, OpContains, OpNotContains:
+			case OpPfx:
+				if _, ok := operand.(string); !ok {
+					return fmt.Errorf("prefix of '%s' is not a string", field)
+				}
+			case OpRange:
+				r, ok := operand.([]interface{})
+				if !ok || len(r) != 2 {
+					return fmt.Errorf("range of '%s' is not a list of two values", field)
+				}
+			default:
+				return fmt.Errorf("unknown operator '%s' in query key '%s'", op, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the item matches the query
+//
+// Maps in the query are ORed and the conditions in a map are ANDed.
+func Match(q []map[string]interface{}, item map[string]interface{}) bool {
+	if len(q) == 0 {
+		return true
+	}
+	for _, m := range q {
+		if matchAll(m, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns the value of a field, nested fields are separated by dots
+func Field(item map[string]interface{}, field string) (interface{}, bool) {
+	var cur interface{} = item
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// reports whether the item matches all conditions
+func matchAll(m map[string]interface{}, item map[string]interface{}) bool {
+	for key, operand := range m {
+		field, op := SplitKey(key)
+		value, ok := Field(item, field)
+		if !matchCondition(op, value, ok, operand) {
+			return false
+		}
+	}
+	return true
+}
+
+// reports whether the value of a field satisfies the condition
+func matchCondition(op string, value interface{}, exists bool, operand interface{}) bool {
+	switch op {
+	case OpNe:
+		return !exists || !reflect.DeepEqual(value, operand)
+	case OpNotContains:
+		return !exists || !contains(value, operand)
+	}
+	if !exists {
+		return false
+	}
+
+	switch op {
+	case OpEq:
+		return reflect.DeepEqual(value, operand)
+	case OpLt:
+		c, ok := compare(value, operand)
+		return ok && c < 0
+	case OpGt:
+		c, ok := compare(value, operand)
+		return ok && c > 0
+	case OpLte:
+		c, ok := compare(value, operand)
+		return ok && c <= 0
+	case OpGte:
+		c, ok := compare(value, operand)
+		return ok && c >= 0
+	case OpPfx:
+		s, ok := value.(string)
+		p, pok := operand.(string)
+		return ok && pok && strings.HasPrefix(s, p)
+	case OpRange:
+		r, ok := operand.([]interface{})
+		if !ok || len(r) != 2 {
+			return false
+		}
+		lo, lok := compare(value, r[0])
+		hi, hok := compare(value, r[1])
+		return lok && hok && lo >= 0 && hi <= 0
+	case OpContains:
+		return contains(value, operand)
+	default:
+		return false
+	}
+}
+
+// compares two numbers or two strings
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	default:
+		return 0, false
+	}
+}
+
+// reports whether a string contains a substring or a list contains a value
+func contains(value, operand interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		s, ok := operand.(string)
+		return ok && strings.Contains(v, s)
+	case []interface{}:
+		for _, e := range v {
+			if reflect.DeepEqual(e, operand) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package base
+
+import (
+	"context"
+	"io"
+)
+
+// API is the interface of a Deta Base service client, implemented by Base
+//
+// Use it to mock a Base in tests, see package basemock, or to decorate a Base with additional behavior.
+type API interface {
+	Put(item interface{}) (string, error)
+	PutCtx(ctx context.Context, item interface{}) (string, error)
+	PutMany(items interface{}, opts ...PutManyOption) (*PutManyOutput, error)
+	PutManyCtx(ctx context.Context, items interface{}, opts ...PutManyOption) (*PutManyOutput, error)
+	BulkPut(items interface{}, opts ...BulkPutOption) ([]PutResult, error)
+	BulkPutCtx(ctx context.Context, items interface{}, opts ...BulkPutOption) ([]PutResult, error)
+	Get(key string, dest interface{}) error
+	GetCtx(ctx context.Context, key string, dest interface{}) error
+	Insert(item interface{}) (string, error)
+	InsertCtx(ctx context.Context, item interface{}) (string, error)
+	Update(key string, updates Updates) error
+	UpdateCtx(ctx context.Context, key string, updates Updates) error
+	Delete(key string) error
+	DeleteCtx(ctx context.Context, key string) error
+	Fetch(i *FetchInput) (string, error)
+	FetchCtx(ctx context.Context, i *FetchInput) (string, error)
+	Iter(q Query, opts ...IterOption) *Iterator
+	IterCtx(ctx context.Context, q Query, opts ...IterOption) *Iterator
+	FetchAll(q Query, dest interface{}, opts ...IterOption) error
+	FetchAllCtx(ctx context.Context, q Query, dest interface{}, opts ...IterOption) error
+	Export(w io.Writer, format Format, q Query, opts ...ExportOption) (int, error)
+	ExportCtx(ctx context.Context, w io.Writer, format Format, q Query, opts ...ExportOption) (int, error)
+	Import(r io.Reader, format Format, opts ...ImportOption) (*ImportOutput, error)
+	ImportCtx(ctx context.Context, r io.Reader, format Format, opts ...ImportOption) (*ImportOutput, error)
+}
+
+var _ API = (*Base)(nil)
+
+// Decorator wraps an API with additional behavior, like caching, logging or metrics
+type Decorator func(API) API
+
+// Decorate returns the API wrapped with the decorators.
+//
+// The first decorator is the outermost, it handles calls first.
+//
+//	users, err := base.New(d, "users")
+//	if err != nil {
+//		return err
+//	}
+//	api := base.Decorate(users, withLogging, withMetrics)
+func Decorate(api API, decorators ...Decorator) API {
+	for n := len(decorators) - 1; n >= 0; n-- {
+		api = decorators[n](api)
+	}
+	return api
+}
@@ -1,11 +1,14 @@
 package base
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/deta/deta-go/deta"
 	"github.com/deta/deta-go/internal/client"
@@ -13,6 +16,8 @@ import (
 
 const (
 	baseEndpoint = "https://database.deta.sh/v1"
+	// maximum number of items in a single put request
+	maxPutItems = 25
 )
 
 // Base is a Deta Base service client that offers the API to make requests to Deta Base
@@ -37,6 +42,8 @@ type baseItem map[string]interface{}
 //	}
 //
 // The example above fetches items where 'active' is true OR 'age' is less than 32.
+//
+// Use Match to evaluate a query against an item locally.
 type Query []map[string]interface{}
 
 // Updates is a datatype to provide updates to an item in an Update operation
@@ -54,7 +61,10 @@ func New(d *deta.Deta, baseName string) (*Base, error) {
 	parts := strings.Split(projectKey, "_")
 	projectID := parts[0]
 
-	rootEndpoint := os.Getenv("DETA_BASE_ROOT_ENDPOINT")
+	rootEndpoint := d.BaseRootEndpoint
+	if rootEndpoint == "" {
+		rootEndpoint = os.Getenv("DETA_BASE_ROOT_ENDPOINT")
+	}
 	if rootEndpoint == "" {
 		rootEndpoint = baseEndpoint
 	}
@@ -65,7 +75,7 @@ func New(d *deta.Deta, baseName string) (*Base, error) {
 			AuthType:    "api-key",
 			HeaderKey:   "X-API-Key",
 			HeaderValue: projectKey,
-		}),
+		}, d.HTTPClient, d.RetryPolicy),
 	}, nil
 }
 
@@ -85,6 +95,36 @@ func (b *Base) removeEmptyKey(bi baseItem) error {
 	}
 }
 
+// characters of generated keys
+const keyChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// length of generated keys
+const keyLength = 12
+
+// returns a new random key, like the keys generated by the database
+func newKey() (string, error) {
+	// bytes from the largest multiple of the number of characters are discarded,
+	// so that every character is equally likely
+	limit := 256 - 256%len(keyChars)
+	key := make([]byte, 0, keyLength)
+	b := make([]byte, keyLength)
+	for len(key) < keyLength {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) >= limit {
+				continue
+			}
+			key = append(key, keyChars[int(c)%len(keyChars)])
+			if len(key) == keyLength {
+				break
+			}
+		}
+	}
+	return string(key), nil
+}
+
 func (b *Base) modifyItem(item interface{}) (baseItem, error) {
 	data, err := json.Marshal(item)
 	if err != nil {
@@ -127,14 +167,60 @@ type putResponse struct {
 	Failed    map[string][]baseItem `json:"failed"`
 }
 
-func (b *Base) put(items []baseItem) ([]string, error) {
+// FailedItem an item that failed to be put in the database
+type FailedItem struct {
+	// the item as sent to the database
+	Item map[string]interface{}
+	// reason of the failure
+	Reason string
+}
+
+// PutManyOutput output of PutMany operation
+type PutManyOutput struct {
+	// keys of the items put in the database
+	Processed []string
+	// items that failed to be put in the database
+	Failed []*FailedItem
+}
+
+// PartialFailureError is returned if some of the items of a put operation failed
+//
+// It matches deta.ErrItemsFailed with errors.Is.
+type PartialFailureError struct {
+	// items that failed to be put in the database
+	Failed []*FailedItem
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%v: %d item(s) failed", deta.ErrItemsFailed, len(e.Failed))
+}
+
+// Unwrap returns deta.ErrItemsFailed
+func (e *PartialFailureError) Unwrap() error {
+	return deta.ErrItemsFailed
+}
+
+// reason of items reported as failed by the database
+const failedItemReason = "item not processed by the database"
+
+func (b *Base) put(ctx context.Context, items []baseItem) (*PutManyOutput, error) {
 	body := map[string]interface{}{
 		"items": items,
 	}
+	// a retry of items without a key would put them again under new keys
+	idempotent := true
+	for _, item := range items {
+		if _, ok := item["key"]; !ok {
+			idempotent = false
+			break
+		}
+	}
 	o, err := b.client.Request(&client.RequestInput{
-		Path:   "/items",
-		Method: "PUT",
-		Body:   body,
+		Context:    ctx,
+		Path:       "/items",
+		Method:     "PUT",
+		Idempotent: idempotent,
+		Body:       body,
 	})
 	if err != nil {
 		return nil, err
@@ -146,12 +232,73 @@ func (b *Base) put(items []baseItem) ([]string, error) {
 		return nil, err
 	}
 
-	var keys []string
+	out := &PutManyOutput{}
 	for _, item := range pr.Processed["items"] {
-		keys = append(keys, item["key"].(string))
+		out.Processed = append(out.Processed, item["key"].(string))
+	}
+	for _, item := range pr.Failed["items"] {
+		out.Failed = append(out.Failed, &FailedItem{
+			Item:   item,
+			Reason: failedItemReason,
+		})
+	}
+	return out, nil
+}
+
+// matches the output of a put operation to the items
+//
+// Items are matched by their keys, at most one item may be sent without a key.
+// Returns the result of every item in the order of the items.
+func putResults(items []baseItem, out *PutManyOutput) []PutResult {
+	results := make([]PutResult, len(items))
+
+	explicitKeys := make(map[string]bool)
+	for _, item := range items {
+		if key, ok := item["key"].(string); ok {
+			explicitKeys[key] = true
+		}
+	}
+	processed := make(map[string]bool)
+	var generatedKeys []string
+	for _, key := range out.Processed {
+		if explicitKeys[key] {
+			processed[key] = true
+		} else {
+			generatedKeys = append(generatedKeys, key)
+		}
+	}
+	failed := make(map[string]*FailedItem)
+	var unkeyedFailed []*FailedItem
+	for _, f := range out.Failed {
+		if key, ok := f.Item["key"].(string); ok && explicitKeys[key] {
+			failed[key] = f
+		} else {
+			unkeyedFailed = append(unkeyedFailed, f)
+		}
 	}
 
-	return keys, nil
+	for n, item := range items {
+		if key, ok := item["key"].(string); ok {
+			if f, ok := failed[key]; ok {
+				results[n].Err = fmt.Errorf("%w: %s", deta.ErrItemsFailed, f.Reason)
+			} else if processed[key] {
+				results[n].Key = key
+			} else {
+				results[n].Err = fmt.Errorf("%w: %s", deta.ErrItemsFailed, failedItemReason)
+			}
+			continue
+		}
+
+		// the item without a key failed if an item without a key failed
+		if len(unkeyedFailed) > 0 {
+			results[n].Err = fmt.Errorf("%w: %s", deta.ErrItemsFailed, unkeyedFailed[0].Reason)
+		} else if len(generatedKeys) > 0 {
+			results[n].Key = generatedKeys[0]
+		} else {
+			results[n].Err = fmt.Errorf("%w: %s", deta.ErrItemsFailed, failedItemReason)
+		}
+	}
+	return results
 }
 
 // Put an item in the database.
@@ -162,6 +309,11 @@ func (b *Base) put(items []baseItem) ([]string, error) {
 // If the 'key' is provided in the item, a key is autogenerated.
 // Returns the key of the item that was put in the database.
 func (b *Base) Put(item interface{}) (string, error) {
+	return b.PutCtx(context.Background(), item)
+}
+
+// PutCtx is like Put but uses the provided context for the request.
+func (b *Base) PutCtx(ctx context.Context, item interface{}) (string, error) {
 	if item == nil {
 		return "", nil
 	}
@@ -172,42 +324,113 @@ func (b *Base) Put(item interface{}) (string, error) {
 		return "", err
 	}
 
-	putKeys, err := b.put(modifiedItems)
+	out, err := b.put(ctx, modifiedItems)
 	if err != nil {
 		return "", err
 	}
-	return putKeys[0], nil
+	res := putResults(modifiedItems, out)[0]
+	return res.Key, res.Err
+}
+
+// options of a PutMany operation
+type putManyOptions struct {
+	failedRetries int
 }
 
+// PutManyOption is a functional option for a PutMany operation
+type PutManyOption func(*putManyOptions)
+
+// WithFailedRetries option for retrying the items that failed to be put, up to retries times
+//
+// Retries are delayed with an exponential backoff starting at 100 milliseconds.
+func WithFailedRetries(retries int) PutManyOption {
+	return func(o *putManyOptions) {
+		o.failedRetries = retries
+	}
+}
+
+// delay before the first retry of failed items
+const failedRetryBackoff = 100 * time.Millisecond
+
 // PutMany puts multiple items in the database.
 //
-// Puts at most 25 items in a single request.
+// Puts at most 25 items in a single request, use BulkPut for more items.
 // The items should be a slice.
 // Each item in the slice is treated similarly as the input to the Put operation.
-// Returns the slice of keys of the items put in the database.
-func (b *Base) PutMany(items interface{}) ([]string, error) {
+// Returns the keys of the items put in the database and the items that failed.
+// If some items failed, the output is returned together with a *PartialFailureError.
+func (b *Base) PutMany(items interface{}, opts ...PutManyOption) (*PutManyOutput, error) {
+	return b.PutManyCtx(context.Background(), items, opts...)
+}
+
+// PutManyCtx is like PutMany but uses the provided context for the requests.
+func (b *Base) PutManyCtx(ctx context.Context, items interface{}, opts ...PutManyOption) (*PutManyOutput, error) {
+	o := &putManyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	modifiedItems, err := b.modifyItems(items)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(modifiedItems) == 0 {
-		return nil, nil
+		return &PutManyOutput{}, nil
 	}
-	if len(modifiedItems) > 25 {
+	if len(modifiedItems) > maxPutItems {
 		return nil, deta.ErrTooManyItems
 	}
-	return b.put(modifiedItems)
+
+	out, err := b.put(ctx, modifiedItems)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := failedRetryBackoff
+	for retry := 0; retry < o.failedRetries && len(out.Failed) > 0; retry++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return out, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+
+		failedItems := make([]baseItem, len(out.Failed))
+		for n, f := range out.Failed {
+			failedItems[n] = f.Item
+		}
+		retryOut, err := b.put(ctx, failedItems)
+		if err != nil {
+			return out, err
+		}
+		out.Processed = append(out.Processed, retryOut.Processed...)
+		out.Failed = retryOut.Failed
+	}
+
+	if len(out.Failed) > 0 {
+		return out, &PartialFailureError{Failed: out.Failed}
+	}
+	return out, nil
 }
 
 // Get an item from the database.
 //
 // The item is scanned onto `dest`.
 func (b *Base) Get(key string, dest interface{}) error {
+	return b.GetCtx(context.Background(), key, dest)
+}
+
+// GetCtx is like Get but uses the provided context for the request.
+func (b *Base) GetCtx(ctx context.Context, key string, dest interface{}) error {
 	escapedKey := url.PathEscape(key)
 	o, err := b.client.Request(&client.RequestInput{
-		Path:   fmt.Sprintf("/items/%s", escapedKey),
-		Method: "GET",
+		Context:    ctx,
+		Path:       fmt.Sprintf("/items/%s", escapedKey),
+		Method:     "GET",
+		Idempotent: true,
 	})
 	if err != nil {
 		return err
@@ -228,6 +451,11 @@ type insertRequest struct {
 // The item is treated similarly as the input to the Put operation.
 // Returns the key of the item inserted in the database.
 func (b *Base) Insert(item interface{}) (string, error) {
+	return b.InsertCtx(context.Background(), item)
+}
+
+// InsertCtx is like Insert but uses the provided context for the request.
+func (b *Base) InsertCtx(ctx context.Context, item interface{}) (string, error) {
 	modifiedItem, err := b.modifyItem(item)
 	if err != nil {
 		return "", err
@@ -238,9 +466,10 @@ func (b *Base) Insert(item interface{}) (string, error) {
 	}
 
 	o, err := b.client.Request(&client.RequestInput{
-		Path:   "/items",
-		Method: "POST",
-		Body:   ir,
+		Context: ctx,
+		Path:    "/items",
+		Method:  "POST",
+		Body:    ir,
 	})
 
 	if err != nil {
@@ -293,14 +522,20 @@ func (b *Base) updatesToUpdateRequest(updates Updates) *updateRequest {
 //
 // Updates according to the the provided 'updates'.
 func (b *Base) Update(key string, updates Updates) error {
+	return b.UpdateCtx(context.Background(), key, updates)
+}
+
+// UpdateCtx is like Update but uses the provided context for the request.
+func (b *Base) UpdateCtx(ctx context.Context, key string, updates Updates) error {
 	// escape key
 	escapedKey := url.PathEscape(key)
 
 	ur := b.updatesToUpdateRequest(updates)
 	_, err := b.client.Request(&client.RequestInput{
-		Path:   fmt.Sprintf("/items/%s", escapedKey),
-		Method: "PATCH",
-		Body:   ur,
+		Context: ctx,
+		Path:    fmt.Sprintf("/items/%s", escapedKey),
+		Method:  "PATCH",
+		Body:    ur,
 	})
 	if err != nil {
 		return err
@@ -312,12 +547,19 @@ func (b *Base) Update(key string, updates Updates) error {
 //
 // If the key does not exist, a nil error is returned.
 func (b *Base) Delete(key string) error {
+	return b.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx is like Delete but uses the provided context for the request.
+func (b *Base) DeleteCtx(ctx context.Context, key string) error {
 	// escape the key
 	escapedKey := url.PathEscape(key)
 
 	_, err := b.client.Request(&client.RequestInput{
-		Path:   fmt.Sprintf("/items/%s", escapedKey),
-		Method: "DELETE",
+		Context:    ctx,
+		Path:       fmt.Sprintf("/items/%s", escapedKey),
+		Method:     "DELETE",
+		Idempotent: true,
 	})
 	if err != nil {
 		return err
@@ -342,11 +584,13 @@ type fetchResponse struct {
 	Items  []interface{} `json:"items"`
 }
 
-func (b *Base) fetch(req *fetchRequest) (*fetchResponse, error) {
+func (b *Base) fetch(ctx context.Context, req *fetchRequest) (*fetchResponse, error) {
 	o, err := b.client.Request(&client.RequestInput{
-		Path:   "/query",
-		Method: "POST",
-		Body:   req,
+		Context:    ctx,
+		Path:       "/query",
+		Method:     "POST",
+		Idempotent: true,
+		Body:       req,
 	})
 	if err != nil {
 		return nil, err
@@ -381,6 +625,11 @@ type FetchInput struct {
 // Fetch is paginated, returns the last key fetched if further pages are left.
 // Provide the last key in the subsequent fetch operation to fetch remaining pages.
 func (b *Base) Fetch(i *FetchInput) (string, error) {
+	return b.FetchCtx(context.Background(), i)
+}
+
+// FetchCtx is like Fetch but uses the provided context for the request.
+func (b *Base) FetchCtx(ctx context.Context, i *FetchInput) (string, error) {
 	req := &fetchRequest{
 		Query: i.Q,
 	}
@@ -397,7 +646,7 @@ func (b *Base) Fetch(i *FetchInput) (string, error) {
 		req.Sort = &desc
 	}
 
-	res, err := b.fetch(req)
+	res, err := b.fetch(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -1,12 +1,21 @@
 package base
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
 )
 
 type nestedCustomTestStruct struct {
@@ -22,7 +31,26 @@ type customTestStruct struct {
 	TestNested *nestedCustomTestStruct `json:"test_nested_struct"`
 }
 
+// fake deta server used if no test project key is provided
+var testServer *detatest.Server
+
+func TestMain(m *testing.M) {
+	if os.Getenv("DETA_SDK_TEST_PROJECT_KEY") == "" {
+		testServer = detatest.NewServer()
+	}
+	code := m.Run()
+	if testServer != nil {
+		testServer.Close()
+	}
+	os.Exit(code)
+}
+
 func Setup() *Base {
+	if testServer != nil {
+		d, _ := testServer.Deta()
+		base, _ := New(d, "test_base")
+		return base
+	}
 	projectKey := os.Getenv("DETA_SDK_TEST_PROJECT_KEY")
 	baseName := os.Getenv("DETA_SDK_TEST_BASE_NAME")
 	d, _ := deta.New(deta.WithProjectKey(projectKey))
@@ -32,10 +60,7 @@ func Setup() *Base {
 
 func TearDown(b *Base, t *testing.T) {
 	var items []map[string]interface{}
-	_, err := b.Fetch(&FetchInput{
-		Q:    nil,
-		Dest: &items,
-	})
+	err := b.FetchAll(nil, &items)
 	if err != nil {
 		t.Log("Failed to fetch items in teardown, further tests might fail")
 	}
@@ -622,3 +647,729 @@ func TestFetchDesc(t *testing.T) {
 		t.Errorf("Fetched item not equal to expected.\nExpected:\n%v\nGot: %v", revtestItems, dest)
 	}
 }
+
+// recordingAPI records the calls to Put before passing them to the wrapped API
+type recordingAPI struct {
+	API
+	name  string
+	calls *[]string
+}
+
+func (r *recordingAPI) Put(item interface{}) (string, error) {
+	*r.calls = append(*r.calls, r.name)
+	return r.API.Put(item)
+}
+
+func TestDecorate(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	var calls []string
+	recorder := func(name string) Decorator {
+		return func(api API) API {
+			return &recordingAPI{API: api, name: name, calls: &calls}
+		}
+	}
+
+	api := Decorate(base, recorder("outer"), recorder("inner"))
+	key, err := api.Put(map[string]interface{}{"key": "a"})
+	if err != nil {
+		t.Fatalf("Failed to put item with error %v", err)
+	}
+	if key != "a" {
+		t.Errorf("Unexpected key. Expected: %s Got: %s", "a", key)
+	}
+	if !reflect.DeepEqual(calls, []string{"outer", "inner"}) {
+		t.Errorf("Unexpected order of decorators. Expected: %v Got: %v", []string{"outer", "inner"}, calls)
+	}
+}
+
+func TestIter(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	testItems := []customTestStruct{
+		{TestKey: "a", TestValue: "a"},
+		{TestKey: "b", TestValue: "b"},
+		{TestKey: "c", TestValue: "c"},
+		{TestKey: "d", TestValue: "d"},
+		{TestKey: "e", TestValue: "other"},
+	}
+	_, err := base.PutMany(testItems)
+	if err != nil {
+		t.Fatalf("Failed to put items with error %v", err)
+	}
+
+	testCases := []struct {
+		query Query
+		opts  []IterOption
+		keys  []string
+	}{
+		{nil, []IterOption{WithPageSize(2)}, []string{"a", "b", "c", "d", "e"}},
+		{nil, []IterOption{WithPageSize(2), WithLimit(3)}, []string{"a", "b", "c"}},
+		{nil, []IterOption{WithPageSize(2), WithDesc()}, []string{"e", "d", "c", "b", "a"}},
+		{nil, []IterOption{WithLastKey("c")}, []string{"d", "e"}},
+		{Query{{"test_value?ne": "other"}}, []IterOption{WithPageSize(1)}, []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tc := range testCases {
+		var keys []string
+		it := base.Iter(tc.query, tc.opts...)
+		for it.Next() {
+			var item customTestStruct
+			if err := it.Scan(&item); err != nil {
+				t.Fatalf("Failed to scan item with error %v", err)
+			}
+			keys = append(keys, item.TestKey)
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("Unexpected iteration error %v", err)
+		}
+		if !reflect.DeepEqual(tc.keys, keys) {
+			t.Errorf("Iterated keys not equal.\nExpected:\n%v\nGot:\n%v", tc.keys, keys)
+		}
+	}
+}
+
+func TestIterCancel(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := base.IterCtx(ctx, nil)
+	if it.Next() {
+		t.Errorf("Expected no items from a cancelled iterator")
+	}
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.Canceled, it.Err())
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	testItems := []customTestStruct{
+		{TestKey: "a", TestValue: "a"},
+		{TestKey: "b", TestValue: "b"},
+		{TestKey: "c", TestValue: "c"},
+	}
+	_, err := base.PutMany(testItems)
+	if err != nil {
+		t.Fatalf("Failed to put items with error %v", err)
+	}
+
+	var dest []customTestStruct
+	err = base.FetchAll(nil, &dest, WithPageSize(1))
+	if err != nil {
+		t.Fatalf("Failed to fetch all items with error %v", err)
+	}
+	if !reflect.DeepEqual(testItems, dest) {
+		t.Errorf("Fetched items not equal to expected.\nExpected:\n%v\nGot:\n%v", testItems, dest)
+	}
+}
+
+func TestBulkPut(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	var testItems []map[string]interface{}
+	for n := 0; n < 60; n++ {
+		item := map[string]interface{}{"value": n}
+		// every third item has an autogenerated key
+		if n%3 != 0 {
+			item["key"] = fmt.Sprintf("key_%02d", n)
+		}
+		testItems = append(testItems, item)
+	}
+
+	results, err := base.BulkPut(testItems, WithConcurrency(2))
+	if err != nil {
+		t.Fatalf("Failed to bulk put items with error %v", err)
+	}
+	if len(results) != len(testItems) {
+		t.Fatalf("Unexpected number of results. Expected: %d Got: %d", len(testItems), len(results))
+	}
+	for n, res := range results {
+		if res.Err != nil {
+			t.Errorf("Unexpected error for item %d: %v", n, res.Err)
+			continue
+		}
+		if key, ok := testItems[n]["key"]; ok && key != res.Key {
+			t.Errorf("Unexpected key for item %d. Expected: %v Got: %s", n, key, res.Key)
+		}
+		var dest map[string]interface{}
+		if err := base.Get(res.Key, &dest); err != nil {
+			t.Errorf("Failed to get item with key %s", res.Key)
+		}
+		if dest["value"] != float64(n) {
+			t.Errorf("Unexpected value of item %d. Expected: %d Got: %v", n, n, dest["value"])
+		}
+	}
+}
+
+func TestPutManyFailedItems(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Failed items can only be simulated with the fake server")
+	}
+	base := Setup()
+	defer TearDown(base, t)
+
+	// fail items with the value "fail" the first time they are put
+	failures := make(map[string]int)
+	testServer.FailPutItem = func(item map[string]interface{}) bool {
+		if item["value"] != "fail" {
+			return false
+		}
+		key, _ := item["key"].(string)
+		failures[key]++
+		return failures[key] == 1
+	}
+	defer func() { testServer.FailPutItem = nil }()
+
+	testItems := []map[string]interface{}{
+		{"key": "a", "value": "ok"},
+		{"key": "b", "value": "fail"},
+		{"key": "c", "value": "ok"},
+	}
+
+	out, err := base.PutMany(testItems)
+	var partialErr *PartialFailureError
+	if !errors.As(err, &partialErr) || !errors.Is(err, deta.ErrItemsFailed) {
+		t.Fatalf("Unexpected error value. Expected a partial failure Got: %v", err)
+	}
+	if !reflect.DeepEqual(out.Processed, []string{"a", "c"}) {
+		t.Errorf("Unexpected processed keys. Expected: %v Got: %v", []string{"a", "c"}, out.Processed)
+	}
+	if len(out.Failed) != 1 || out.Failed[0].Item["key"] != "b" || out.Failed[0].Reason == "" {
+		t.Errorf("Unexpected failed items %v", out.Failed)
+	}
+	if !reflect.DeepEqual(partialErr.Failed, out.Failed) {
+		t.Errorf("Failed items of the error not equal to the output")
+	}
+
+	testItems[1]["key"] = "d"
+	out, err = base.PutMany(testItems, WithFailedRetries(1))
+	if err != nil {
+		t.Fatalf("Failed to put items with retries with error %v", err)
+	}
+	if !reflect.DeepEqual(out.Processed, []string{"a", "c", "d"}) {
+		t.Errorf("Unexpected processed keys. Expected: %v Got: %v", []string{"a", "c", "d"}, out.Processed)
+	}
+	if len(out.Failed) != 0 {
+		t.Errorf("Unexpected failed items %v", out.Failed)
+	}
+
+	// a failed item of Put is returned as an error
+	_, err = base.Put(map[string]interface{}{"key": "e", "value": "fail"})
+	if !errors.Is(err, deta.ErrItemsFailed) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrItemsFailed, err)
+	}
+
+	// failed items without keys are matched to their results
+	results, err := base.BulkPut([]map[string]interface{}{
+		{"value": "ok"},
+		{"value": "fail"},
+		{"value": "ok"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to bulk put items with error %v", err)
+	}
+	if results[0].Err != nil || results[0].Key == "" || results[2].Err != nil || results[2].Key == "" {
+		t.Errorf("Unexpected results for processed items %v", results)
+	}
+	if !errors.Is(results[1].Err, deta.ErrItemsFailed) || results[1].Key != "" {
+		t.Errorf("Unexpected result for failed item %v", results[1])
+	}
+
+	// equal items without keys are matched to their own results
+	puts := 0
+	testServer.FailPutItem = func(item map[string]interface{}) bool {
+		puts++
+		return puts == 2
+	}
+	results, err = base.BulkPut([]map[string]interface{}{
+		{"value": "same"},
+		{"value": "same"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to bulk put items with error %v", err)
+	}
+	if results[0].Err != nil || results[0].Key == "" {
+		t.Errorf("Unexpected result for processed item %v", results[0])
+	}
+	if !errors.Is(results[1].Err, deta.ErrItemsFailed) || results[1].Key != "" {
+		t.Errorf("Unexpected result for failed item %v", results[1])
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	testCases := []struct {
+		qb       *QueryBuilder
+		expected Query
+	}{
+		{
+			qb:       Where("name").Eq("jimmy"),
+			expected: Query{{"name": "jimmy"}},
+		},
+		{
+			qb: Where("age").Lt(32).And("active").Eq(true).And("address.city").Ne("berlin"),
+			expected: Query{
+				{"age?lt": 32, "active": true, "address.city?ne": "berlin"},
+			},
+		},
+		{
+			qb: Where("age").Gte(18).And("age").Lte(65).
+				Or(Where("name").Pfx("j"), Where("score").Gt(9.5)),
+			expected: Query{
+				{"age?gte": 18, "age?lte": 65},
+				{"name?pfx": "j"},
+				{"score?gt": 9.5},
+			},
+		},
+		{
+			// And after Or applies to the last ORed query
+			qb: Where("age").Lt(18).Or(Where("name").Pfx("j")).And("active").Eq(true),
+			expected: Query{
+				{"age?lt": 18},
+				{"name?pfx": "j", "active": true},
+			},
+		},
+		{
+			qb: Where("age").Range(18, 65).OrWhere("hobbies").Contains("go").And("name").NotContains("x"),
+			expected: Query{
+				{"age?r": []interface{}{18, 65}},
+				{"hobbies?contains": "go", "name?not_contains": "x"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		q, err := tc.qb.Query()
+		if err != nil {
+			t.Fatalf("Failed to build query: %v", err)
+		}
+		if !reflect.DeepEqual(q, tc.expected) {
+			t.Errorf("Built query is not as expected. Expected: %v Got: %v", tc.expected, q)
+		}
+	}
+}
+
+func TestQueryBuilderZeroValue(t *testing.T) {
+	var qb QueryBuilder
+	if q, err := qb.Query(); err != nil || len(q) != 0 {
+		t.Errorf("Unexpected empty query. Expected: %v Got: %v (error: %v)", Query{}, q, err)
+	}
+
+	qb.And("age").Lt(32).And("active").Eq(true)
+	expected := Query{{"age?lt": 32, "active": true}}
+	q, err := qb.Query()
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	if !reflect.DeepEqual(q, expected) {
+		t.Errorf("Built query is not as expected. Expected: %v Got: %v", expected, q)
+	}
+}
+
+func TestQueryBuilderErrors(t *testing.T) {
+	testCases := []*QueryBuilder{
+		Where("").Eq(1),
+		Where("age?lt").Eq(1),
+		Where("address..city").Eq(1),
+		Where("age").Lt(true),
+		Where("age").Gt(nil),
+		Where("age").Range(65, 18),
+		Where("age").Range(18, "65"),
+		Where("hobbies").Contains(nil),
+		Where("age").Eq(make(chan int)),
+		Where("age").Lt(32).And("age").Lt(40),
+		Where("name").Eq("jimmy").Or(Where("age").Lte([]int{1})),
+	}
+
+	for _, qb := range testCases {
+		q, err := qb.Query()
+		if !errors.Is(err, deta.ErrBadQuery) {
+			t.Errorf("Query %v did not fail as expected. Expected: %v Got: %v", q, deta.ErrBadQuery, err)
+		}
+	}
+}
+
+func TestQueryBuilderFetch(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	items := []*customTestStruct{
+		{TestKey: "a", TestValue: "jimmy"},
+		{TestKey: "b", TestValue: "jane"},
+		{TestKey: "c", TestValue: "alice"},
+	}
+	if _, err := base.PutMany(items); err != nil {
+		t.Fatalf("Failed to put items: %v", err)
+	}
+
+	q, err := Where("test_value").Pfx("j").And("key").Ne("b").Or(Where("key").Eq("c")).Query()
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	var got []*customTestStruct
+	if err := base.FetchAll(q, &got); err != nil {
+		t.Fatalf("Failed to fetch items: %v", err)
+	}
+	expected := []*customTestStruct{items[0], items[2]}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Fetched items are not as expected. Expected: %v Got: %v", expected, got)
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	item := map[string]interface{}{
+		"key":     "jimmy_neutron",
+		"name":    "jimmy",
+		"age":     20,
+		"active":  true,
+		"likes":   []string{"science", "go"},
+		"address": map[string]interface{}{"city": "berlin", "zip": 10115},
+	}
+
+	testCases := []struct {
+		q        Query
+		expected bool
+	}{
+		{nil, true},
+		{Query{{"name": "jimmy"}}, true},
+		{Query{{"name": "jimmy", "age": 21}}, false},
+		{Query{{"name": "jane"}, {"age": 20}}, true},
+		{Query{{"address.city": "berlin"}}, true},
+		{Query{{"address.city?ne": "berlin"}}, false},
+		{Query{{"address.country?ne": "germany"}}, true},
+		{Query{{"likes": []string{"science", "go"}}}, true},
+		{Query{{"age?lt": 20}}, false},
+		{Query{{"age?lte": 20}}, true},
+		{Query{{"age?gt": 19.5}}, true},
+		{Query{{"age?gte": 21}}, false},
+		{Query{{"name?pfx": "ji"}}, true},
+		{Query{{"name?pfx": "ja"}}, false},
+		{Query{{"age?r": []int{18, 20}}}, true},
+		{Query{{"age?r": []int{21, 30}}}, false},
+		{Query{{"name?contains": "imm"}}, true},
+		{Query{{"likes?contains": "go"}}, true},
+		{Query{{"likes?contains": "art"}}, false},
+		{Query{{"likes?not_contains": "art"}}, true},
+		{Query{{"address.zip?lt": "a"}}, false},
+		{Query{{"missing": nil}}, false},
+	}
+
+	for _, tc := range testCases {
+		ok, err := tc.q.Match(item)
+		if err != nil {
+			t.Fatalf("Failed to match query %v: %v", tc.q, err)
+		}
+		if ok != tc.expected {
+			t.Errorf("Query %v did not match as expected. Expected: %v Got: %v", tc.q, tc.expected, ok)
+		}
+	}
+
+	// built queries and structs
+	q, err := Where("test_nested_struct.test_int").Gte(10).And("test_value").Pfx("te").Query()
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	ok, err := q.Match(&customTestStruct{
+		TestValue:  "test",
+		TestNested: &nestedCustomTestStruct{TestInt: 10},
+	})
+	if err != nil || !ok {
+		t.Errorf("Struct did not match built query. Expected: %v Got: %v (error: %v)", true, ok, err)
+	}
+
+	if _, err := (Query{{"age?unknown": 1}}).Match(item); !errors.Is(err, deta.ErrBadQuery) {
+		t.Errorf("Unexpected error for bad query. Expected: %v Got: %v", deta.ErrBadQuery, err)
+	}
+	if _, err := (Query{{"age": 1}}).Match([]string{"a"}); !errors.Is(err, deta.ErrBadItem) {
+		t.Errorf("Unexpected error for bad item. Expected: %v Got: %v", deta.ErrBadItem, err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	var testItems []map[string]interface{}
+	for n := 0; n < 30; n++ {
+		testItems = append(testItems, map[string]interface{}{
+			"key":    fmt.Sprintf("key_%02d", n),
+			"value":  float64(n),
+			"name":   fmt.Sprintf("item %d", n),
+			"active": n%2 == 0,
+		})
+	}
+	// values that are ambiguous in csv
+	testItems[0]["name"] = "12"
+	testItems[1]["name"] = ""
+	testItems[2]["name"] = "true"
+	testItems[3]["name"] = []interface{}{"a", float64(1)}
+	testItems[4]["name"] = map[string]interface{}{"first": "jimmy"}
+	testItems[5]["name"] = nil
+	delete(testItems[6], "name")
+	testItems[7]["key"] = "123"
+	if _, err := base.BulkPut(testItems); err != nil {
+		t.Fatalf("Failed to put items with error %v", err)
+	}
+
+	for _, format := range []Format{FormatJSONL, FormatJSON, FormatCSV} {
+		var buf bytes.Buffer
+		count, err := base.Export(&buf, format, nil)
+		if err != nil {
+			t.Fatalf("Failed to export items as %s with error %v", format, err)
+		}
+		if count != len(testItems) {
+			t.Errorf("Unexpected number of exported items as %s. Expected: %d Got: %d", format, len(testItems), count)
+		}
+		TearDown(base, t)
+
+		var progress []ImportProgress
+		out, err := base.Import(&buf, format, WithImportProgress(func(p ImportProgress) {
+			progress = append(progress, p)
+		}))
+		if err != nil {
+			t.Fatalf("Failed to import items as %s with error %v", format, err)
+		}
+		if out.Processed != len(testItems) || len(out.Failed) != 0 {
+			t.Errorf("Unexpected import output as %s %+v", format, out)
+		}
+		expectedProgress := []ImportProgress{{Processed: 25}, {Processed: 30}}
+		if !reflect.DeepEqual(progress, expectedProgress) {
+			t.Errorf("Unexpected import progress as %s. Expected: %v Got: %v", format, expectedProgress, progress)
+		}
+
+		for _, item := range testItems {
+			var dest map[string]interface{}
+			if err := base.Get(item["key"].(string), &dest); err != nil {
+				t.Fatalf("Failed to get imported item with key %s as %s: %v", item["key"], format, err)
+			}
+			if !reflect.DeepEqual(dest, item) {
+				t.Errorf("Imported item as %s not equal to the exported item. Expected: %v Got: %v", format, item, dest)
+			}
+		}
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	testItems := []map[string]interface{}{
+		{"key": "a", "value": 1, "extra": "x"},
+		{"key": "b", "value": 2},
+		{"key": "c", "value": 3},
+	}
+	if _, err := base.PutMany(testItems); err != nil {
+		t.Fatalf("Failed to put items with error %v", err)
+	}
+
+	var buf bytes.Buffer
+	count, err := base.Export(&buf, FormatCSV, Query{{"value?lt": 3}}, WithColumns("key", "value"))
+	if err != nil {
+		t.Fatalf("Failed to export items with error %v", err)
+	}
+	expected := "key,value\na,1\nb,2\n"
+	if count != 2 || buf.String() != expected {
+		t.Errorf("Unexpected export output. Expected: %d %q Got: %d %q", 2, expected, count, buf.String())
+	}
+
+	buf.Reset()
+	if _, err := base.Export(&buf, FormatJSON, Query{{"value": 4}}); err != nil {
+		t.Fatalf("Failed to export items with error %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Unexpected export output. Expected: %q Got: %q", "[]\n", buf.String())
+	}
+
+	if _, err := base.Export(&buf, Format("xml"), nil); !errors.Is(err, deta.ErrBadFormat) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadFormat, err)
+	}
+}
+
+func TestImportRegenerateKeys(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	out, err := base.Import(strings.NewReader(`{"key": "a", "value": 1}
+{"value": 2}
+`), FormatJSONL, WithRegenerateKeys())
+	if err != nil {
+		t.Fatalf("Failed to import items with error %v", err)
+	}
+	if out.Processed != 2 {
+		t.Errorf("Unexpected number of imported items. Expected: %d Got: %d", 2, out.Processed)
+	}
+	var items []map[string]interface{}
+	if err := base.FetchAll(nil, &items); err != nil {
+		t.Fatalf("Failed to fetch items with error %v", err)
+	}
+	for _, item := range items {
+		if item["key"] == "a" {
+			t.Errorf("Key of imported item not regenerated")
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	base := Setup()
+	defer TearDown(base, t)
+
+	testCases := []struct {
+		input  string
+		format Format
+	}{
+		{`{"key": "a"}` + "\n[1]\n", FormatJSONL},
+		{`{"key": "a"}` + "\nnull\n", FormatJSONL},
+		{`{"key": "a"}`, FormatJSON},
+		{`[{"key": "a"}, 1]`, FormatJSON},
+		{`[{"key": "a"}`, FormatJSON},
+		{"", FormatJSON},
+		{"key,value\na,1\nb\n", FormatCSV},
+	}
+	for _, tc := range testCases {
+		_, err := base.Import(strings.NewReader(tc.input), tc.format)
+		if !errors.Is(err, deta.ErrBadItem) {
+			t.Errorf("Unexpected error value for %q as %s. Expected: %v Got: %v", tc.input, tc.format, deta.ErrBadItem, err)
+		}
+	}
+
+	if _, err := base.Import(strings.NewReader(""), Format("xml")); !errors.Is(err, deta.ErrBadFormat) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadFormat, err)
+	}
+}
+
+func TestImportFailedItems(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Failed items can only be simulated with the fake server")
+	}
+	base := Setup()
+	defer TearDown(base, t)
+
+	testServer.FailPutItem = func(item map[string]interface{}) bool {
+		return item["value"] == "fail"
+	}
+	defer func() { testServer.FailPutItem = nil }()
+
+	out, err := base.Import(strings.NewReader("key,value\na,ok\nb,fail\nc,ok\n"), FormatCSV)
+	var partialErr *PartialFailureError
+	if !errors.As(err, &partialErr) {
+		t.Fatalf("Unexpected error value. Expected a partial failure Got: %v", err)
+	}
+	if out.Processed != 2 || len(out.Failed) != 1 || out.Failed[0].Item["key"] != "b" {
+		t.Errorf("Unexpected import output %+v", out)
+	}
+}
+
+func TestExportCSVColumnsOfFirstPage(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Pages of more than 1000 items are only tested with the fake server")
+	}
+	base := Setup()
+	defer TearDown(base, t)
+
+	var testItems []map[string]interface{}
+	for n := 0; n < 1001; n++ {
+		testItems = append(testItems, map[string]interface{}{"key": fmt.Sprintf("key_%04d", n), "value": n})
+	}
+	if _, err := base.BulkPut(testItems); err != nil {
+		t.Fatalf("Failed to put items with error %v", err)
+	}
+
+	var buf bytes.Buffer
+	count, err := base.Export(&buf, FormatCSV, nil)
+	if err != nil || count != len(testItems) {
+		t.Fatalf("Unexpected export result. Expected: %d Got: %d, %v", len(testItems), count, err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(testItems)+1 {
+		t.Errorf("Unexpected number of csv lines. Expected: %d Got: %d", len(testItems)+1, lines)
+	}
+
+	// an item of the second page with fields missing in the first page
+	extraItem := map[string]interface{}{"key": "key_1000", "value": float64(1000), "extra": true, "tags": []interface{}{"a"}}
+	if _, err := base.Put(extraItem); err != nil {
+		t.Fatalf("Failed to put item with error %v", err)
+	}
+	buf.Reset()
+	count, err = base.Export(&buf, FormatCSV, nil)
+	if err != nil || count != len(testItems) {
+		t.Fatalf("Unexpected export result. Expected: %d Got: %d, %v", len(testItems), count, err)
+	}
+	TearDown(base, t)
+	if _, err := base.Import(bytes.NewReader(buf.Bytes()), FormatCSV); err != nil {
+		t.Fatalf("Failed to import items with error %v", err)
+	}
+	var dest map[string]interface{}
+	if err := base.Get("key_1000", &dest); err != nil || !reflect.DeepEqual(dest, extraItem) {
+		t.Errorf("Imported item not as expected. Expected: %v Got: %v (error: %v)", extraItem, dest, err)
+	}
+	buf.Reset()
+	if _, err := base.Export(&buf, FormatCSV, nil, WithColumns("key", "extra")); err != nil {
+		t.Errorf("Failed to export items with columns with error %v", err)
+	}
+}
+
+func TestPutRetriesKeyedItems(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d, _ := deta.New(
+		deta.WithProjectKey("project_key"),
+		deta.WithBaseRootEndpoint(srv.URL),
+		deta.WithRetryPolicy(&deta.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}),
+	)
+	base, _ := New(d, "base")
+
+	testCases := []struct {
+		Item     map[string]interface{}
+		Requests int32
+	}{
+		// the server generates the keys of items without a key, a retry could put them twice
+		{Item: map[string]interface{}{"value": "a"}, Requests: 1},
+		{Item: map[string]interface{}{"key": "b", "value": "b"}, Requests: 3},
+	}
+	for _, tc := range testCases {
+		atomic.StoreInt32(&requests, 0)
+		if _, err := base.Put(tc.Item); !errors.Is(err, deta.ErrInternalServerError) {
+			t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrInternalServerError, err)
+		}
+		if got := atomic.LoadInt32(&requests); got != tc.Requests {
+			t.Errorf("Unexpected number of requests for %v. Expected: %d Got: %d", tc.Item, tc.Requests, got)
+		}
+	}
+}
+
+func TestNewKeyUniform(t *testing.T) {
+	counts := make(map[rune]int)
+	keys := 10000
+	for n := 0; n < keys; n++ {
+		key, err := newKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if len(key) != keyLength {
+			t.Fatalf("Unexpected key length. Expected: %d Got: %d", keyLength, len(key))
+		}
+		for _, c := range key {
+			counts[c]++
+		}
+	}
+
+	// a biased character is about 12% more likely, beyond the tolerance
+	expected := keys * keyLength / len(keyChars)
+	for _, c := range keyChars {
+		if diff := counts[c] - expected; diff < -expected/10 || diff > expected/10 {
+			t.Errorf("Character %q is not uniformly distributed. Expected about: %d Got: %d", c, expected, counts[c])
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package basemock provides a mock of the Deta Base service client for tests.
+//
+// Set the function fields of a Base for the methods used by the code under test.
+// A method without a Ctx suffix falls back to the function of its Ctx variant
+// when its own function is not set.
+//
+//	mock := &basemock.Base{
+//		GetCtxFunc: func(ctx context.Context, key string, dest interface{}) error {
+//			return deta.ErrNotFound
+//		},
+//	}
+//	var api base.API = mock
+//
+// IterCtx and FetchAllCtx fall back to iterating with FetchCtx when their functions are not set.
+//
+// Calling a method without a function set panics.
+package basemock
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/deta/deta-go/service/base"
+)
+
+// Base is a mock of base.API
+type Base struct {
+	PutFunc         func(item interface{}) (string, error)
+	PutCtxFunc      func(ctx context.Context, item interface{}) (string, error)
+	PutManyFunc     func(items interface{}, opts ...base.PutManyOption) (*base.PutManyOutput, error)
+	PutManyCtxFunc  func(ctx context.Context, items interface{}, opts ...base.PutManyOption) (*base.PutManyOutput, error)
+	BulkPutFunc     func(items interface{}, opts ...base.BulkPutOption) ([]base.PutResult, error)
+	BulkPutCtxFunc  func(ctx context.Context, items interface{}, opts ...base.BulkPutOption) ([]base.PutResult, error)
+	GetFunc         func(key string, dest interface{}) error
+	GetCtxFunc      func(ctx context.Context, key string, dest interface{}) error
+	InsertFunc      func(item interface{}) (string, error)
+	InsertCtxFunc   func(ctx context.Context, item interface{}) (string, error)
+	UpdateFunc      func(key string, updates base.Updates) error
+	UpdateCtxFunc   func(ctx context.Context, key string, updates base.Updates) error
+	DeleteFunc      func(key string) error
+	DeleteCtxFunc   func(ctx context.Context, key string) error
+	FetchFunc       func(i *base.FetchInput) (string, error)
+	FetchCtxFunc    func(ctx context.Context, i *base.FetchInput) (string, error)
+	IterFunc        func(q base.Query, opts ...base.IterOption) *base.Iterator
+	IterCtxFunc     func(ctx context.Context, q base.Query, opts ...base.IterOption) *base.Iterator
+	FetchAllFunc    func(q base.Query, dest interface{}, opts ...base.IterOption) error
+	FetchAllCtxFunc func(ctx context.Context, q base.Query, dest interface{}, opts ...base.IterOption) error
+	ExportFunc      func(w io.Writer, format base.Format, q base.Query, opts ...base.ExportOption) (int, error)
+	ExportCtxFunc   func(ctx context.Context, w io.Writer, format base.Format, q base.Query, opts ...base.ExportOption) (int, error)
+	ImportFunc      func(r io.Reader, format base.Format, opts ...base.ImportOption) (*base.ImportOutput, error)
+	ImportCtxFunc   func(ctx context.Context, r io.Reader, format base.Format, opts ...base.ImportOption) (*base.ImportOutput, error)
+}
+
+var _ base.API = (*Base)(nil)
+
+// panics for a call to a method without a function set
+func unexpected(method string) {
+	panic(fmt.Sprintf("basemock: unexpected call to Base.%s, %sFunc is not set", method, method))
+}
+
+// Put calls PutFunc, or PutCtxFunc with a background context
+func (m *Base) Put(item interface{}) (string, error) {
+	if m.PutFunc != nil {
+		return m.PutFunc(item)
+	}
+	return m.PutCtx(context.Background(), item)
+}
+
+// PutCtx calls PutCtxFunc
+func (m *Base) PutCtx(ctx context.Context, item interface{}) (string, error) {
+	if m.PutCtxFunc == nil {
+		unexpected("PutCtx")
+	}
+	return m.PutCtxFunc(ctx, item)
+}
+
+// PutMany calls PutManyFunc, or PutManyCtxFunc with a background context
+func (m *Base) PutMany(items interface{}, opts ...base.PutManyOption) (*base.PutManyOutput, error) {
+	if m.PutManyFunc != nil {
+		return m.PutManyFunc(items, opts...)
+	}
+	return m.PutManyCtx(context.Background(), items, opts...)
+}
+
+// PutManyCtx calls PutManyCtxFunc
+func (m *Base) PutManyCtx(ctx context.Context, items interface{}, opts ...base.PutManyOption) (*base.PutManyOutput, error) {
+	if m.PutManyCtxFunc == nil {
+		unexpected("PutManyCtx")
+	}
+	return m.PutManyCtxFunc(ctx, items, opts...)
+}
+
+// BulkPut calls BulkPutFunc, or BulkPutCtxFunc with a background context
+func (m *Base) BulkPut(items interface{}, opts ...base.BulkPutOption) ([]base.PutResult, error) {
+	if m.BulkPutFunc != nil {
+		return m.BulkPutFunc(items, opts...)
+	}
+	return m.BulkPutCtx(context.Background(), items, opts...)
+}
+
+// BulkPutCtx calls BulkPutCtxFunc
+func (m *Base) BulkPutCtx(ctx context.Context, items interface{}, opts ...base.BulkPutOption) ([]base.PutResult, error) {
+	if m.BulkPutCtxFunc == nil {
+		unexpected("BulkPutCtx")
+	}
+	return m.BulkPutCtxFunc(ctx, items, opts...)
+}
+
+// Get calls GetFunc, or GetCtxFunc with a background context
+func (m *Base) Get(key string, dest interface{}) error {
+	if m.GetFunc != nil {
+		return m.GetFunc(key, dest)
+	}
+	return m.GetCtx(context.Background(), key, dest)
+}
+
+// GetCtx calls GetCtxFunc
+func (m *Base) GetCtx(ctx context.Context, key string, dest interface{}) error {
+	if m.GetCtxFunc == nil {
+		unexpected("GetCtx")
+	}
+	return m.GetCtxFunc(ctx, key, dest)
+}
+
+// Insert calls InsertFunc, or InsertCtxFunc with a background context
+func (m *Base) Insert(item interface{}) (string, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(item)
+	}
+	return m.InsertCtx(context.Background(), item)
+}
+
+// InsertCtx calls InsertCtxFunc
+func (m *Base) InsertCtx(ctx context.Context, item interface{}) (string, error) {
+	if m.InsertCtxFunc == nil {
+		unexpected("InsertCtx")
+	}
+	return m.InsertCtxFunc(ctx, item)
+}
+
+// Update calls UpdateFunc, or UpdateCtxFunc with a background context
+func (m *Base) Update(key string, updates base.Updates) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(key, updates)
+	}
+	return m.UpdateCtx(context.Background(), key, updates)
+}
+
+// UpdateCtx calls UpdateCtxFunc
+func (m *Base) UpdateCtx(ctx context.Context, key string, updates base.Updates) error {
+	if m.UpdateCtxFunc == nil {
+		unexpected("UpdateCtx")
+	}
+	return m.UpdateCtxFunc(ctx, key, updates)
+}
+
+// Delete calls DeleteFunc, or DeleteCtxFunc with a background context
+func (m *Base) Delete(key string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(key)
+	}
+	return m.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx calls DeleteCtxFunc
+func (m *Base) DeleteCtx(ctx context.Context, key string) error {
+	if m.DeleteCtxFunc == nil {
+		unexpected("DeleteCtx")
+	}
+	return m.DeleteCtxFunc(ctx, key)
+}
+
+// Fetch calls FetchFunc, or FetchCtxFunc with a background context
+func (m *Base) Fetch(i *base.FetchInput) (string, error) {
+	if m.FetchFunc != nil {
+		return m.FetchFunc(i)
+	}
+	return m.FetchCtx(context.Background(), i)
+}
+
+// FetchCtx calls FetchCtxFunc
+func (m *Base) FetchCtx(ctx context.Context, i *base.FetchInput) (string, error) {
+	if m.FetchCtxFunc == nil {
+		unexpected("FetchCtx")
+	}
+	return m.FetchCtxFunc(ctx, i)
+}
+
+// Iter calls IterFunc, or IterCtx with a background context
+func (m *Base) Iter(q base.Query, opts ...base.IterOption) *base.Iterator {
+	if m.IterFunc != nil {
+		return m.IterFunc(q, opts...)
+	}
+	return m.IterCtx(context.Background(), q, opts...)
+}
+
+// IterCtx calls IterCtxFunc, or returns an iterator fetching pages with FetchCtx
+func (m *Base) IterCtx(ctx context.Context, q base.Query, opts ...base.IterOption) *base.Iterator {
+	if m.IterCtxFunc != nil {
+		return m.IterCtxFunc(ctx, q, opts...)
+	}
+	return base.NewIterator(ctx, m, q, opts...)
+}
+
+// FetchAll calls FetchAllFunc, or FetchAllCtx with a background context
+func (m *Base) FetchAll(q base.Query, dest interface{}, opts ...base.IterOption) error {
+	if m.FetchAllFunc != nil {
+		return m.FetchAllFunc(q, dest, opts...)
+	}
+	return m.FetchAllCtx(context.Background(), q, dest, opts...)
+}
+
+// FetchAllCtx calls FetchAllCtxFunc, or scans all items of IterCtx
+func (m *Base) FetchAllCtx(ctx context.Context, q base.Query, dest interface{}, opts ...base.IterOption) error {
+	if m.FetchAllCtxFunc != nil {
+		return m.FetchAllCtxFunc(ctx, q, dest, opts...)
+	}
+	return m.IterCtx(ctx, q, opts...).ScanAll(dest)
+}
+
+// Export calls ExportFunc, or ExportCtxFunc with a background context
+func (m *Base) Export(w io.Writer, format base.Format, q base.Query, opts ...base.ExportOption) (int, error) {
+	if m.ExportFunc != nil {
+		return m.ExportFunc(w, format, q, opts...)
+	}
+	return m.ExportCtx(context.Background(), w, format, q, opts...)
+}
+
+// ExportCtx calls ExportCtxFunc
+func (m *Base) ExportCtx(ctx context.Context, w io.Writer, format base.Format, q base.Query, opts ...base.ExportOption) (int, error) {
+	if m.ExportCtxFunc == nil {
+		unexpected("ExportCtx")
+	}
+	return m.ExportCtxFunc(ctx, w, format, q, opts...)
+}
+
+// Import calls ImportFunc, or ImportCtxFunc with a background context
+func (m *Base) Import(r io.Reader, format base.Format, opts ...base.ImportOption) (*base.ImportOutput, error) {
+	if m.ImportFunc != nil {
+		return m.ImportFunc(r, format, opts...)
+	}
+	return m.ImportCtx(context.Background(), r, format, opts...)
+}
+
+// ImportCtx calls ImportCtxFunc
+func (m *Base) ImportCtx(ctx context.Context, r io.Reader, format base.Format, opts ...base.ImportOption) (*base.ImportOutput, error) {
+	if m.ImportCtxFunc == nil {
+		unexpected("ImportCtx")
+	}
+	return m.ImportCtxFunc(ctx, r, format, opts...)
+}
@@ -0,0 +1,116 @@
+package base
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// default number of concurrent put requests of a BulkPut operation
+	defaultBulkPutConcurrency = 4
+)
+
+// options of a BulkPut operation
+type bulkPutOptions struct {
+	concurrency int
+}
+
+// BulkPutOption is a functional option for a BulkPut operation
+type BulkPutOption func(*bulkPutOptions)
+
+// WithConcurrency option for setting the maximum number of concurrent put requests, 4 by default
+func WithConcurrency(concurrency int) BulkPutOption {
+	return func(o *bulkPutOptions) {
+		o.concurrency = concurrency
+	}
+}
+
+// PutResult result of putting a single item in a BulkPut operation
+type PutResult struct {
+	// key of the item, empty if the item was not put
+	Key string
+	// error putting the item
+	Err error
+}
+
+// BulkPut puts any number of items in the database.
+//
+// The items are split into requests of at most 25 items, sent concurrently.
+// The items should be a slice, each item is treated similarly as the input to the Put operation.
+// Items without a key get a random key before they are sent, so that the result of every item
+// is known.
+// Returns the result of every item in the order of the input items.
+// The returned error is only non-nil if the items are invalid, failures of single items are
+// reported in their results.
+func (b *Base) BulkPut(items interface{}, opts ...BulkPutOption) ([]PutResult, error) {
+	return b.BulkPutCtx(context.Background(), items, opts...)
+}
+
+// BulkPutCtx is like BulkPut but uses the provided context for the requests.
+func (b *Base) BulkPutCtx(ctx context.Context, items interface{}, opts ...BulkPutOption) ([]PutResult, error) {
+	o := &bulkPutOptions{
+		concurrency: defaultBulkPutConcurrency,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	modifiedItems, err := b.modifyItems(items)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range modifiedItems {
+		if _, ok := item["key"]; ok {
+			continue
+		}
+		if item["key"], err = newKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]PutResult, len(modifiedItems))
+	sem := make(chan struct{}, o.concurrency)
+	var wg sync.WaitGroup
+	for start := 0; start < len(modifiedItems); start += maxPutItems {
+		end := start + maxPutItems
+		if end > len(modifiedItems) {
+			end = len(modifiedItems)
+		}
+
+		// wait for a free slot, do not start new requests once the context is done
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for n := start; n < len(modifiedItems); n++ {
+				results[n].Err = err
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(batch []baseItem, results []PutResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.putBatch(ctx, batch, results)
+		}(modifiedItems[start:end], results[start:end])
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// puts a batch of items and stores the result of every item in results
+func (b *Base) putBatch(ctx context.Context, batch []baseItem, results []PutResult) {
+	out, err := b.put(ctx, batch)
+	if err != nil {
+		for n := range results {
+			results[n].Err = err
+		}
+		return
+	}
+	copy(results, putResults(batch, out))
+}
@@ -0,0 +1,569 @@
+package base
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// Format is the format of exported and imported items
+type Format string
+
+const (
+	// FormatJSONL one json object per line
+	FormatJSONL Format = "jsonl"
+	// FormatJSON a json array of objects
+	FormatJSON Format = "json"
+	// FormatCSV a csv header with the fields, followed by one record per item
+	//
+	// The key is written as is. Other values are written as json, except strings that are not
+	// valid json themselves. An empty cell is a missing field.
+	//
+	// Exports without WithColumns take the columns from the first fetched page, followed by a
+	// __extra column. Fields of later items that are not columns are written to the __extra
+	// column as a json object, and merged back into the items by Import.
+	FormatCSV Format = "csv"
+)
+
+// validates the format
+func (f Format) validate() error {
+	switch f {
+	case FormatJSONL, FormatJSON, FormatCSV:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown format '%s'", deta.ErrBadFormat, f)
+	}
+}
+
+// options of an Export operation
+type exportOptions struct {
+	columns []string
+}
+
+// ExportOption is a functional option for an Export operation
+type ExportOption func(*exportOptions)
+
+// WithColumns option for setting the fields exported as columns in FormatCSV
+//
+// Other fields are not exported. Without columns, the columns are the fields of the items of
+// the first fetched page, and the other fields are exported in the __extra column.
+func WithColumns(columns ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.columns = columns
+	}
+}
+
+// Export writes the items matching the query to w in the format.
+//
+// Items are fetched and written page by page, a nil query exports all items.
+// Returns the number of exported items.
+func (b *Base) Export(w io.Writer, format Format, q Query, opts ...ExportOption) (int, error) {
+	return b.ExportCtx(context.Background(), w, format, q, opts...)
+}
+
+// ExportCtx is like Export but uses the provided context for the requests.
+func (b *Base) ExportCtx(ctx context.Context, w io.Writer, format Format, q Query, opts ...ExportOption) (int, error) {
+	if err := format.validate(); err != nil {
+		return 0, err
+	}
+	o := &exportOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	bw := bufio.NewWriter(w)
+	var enc itemEncoder
+	switch format {
+	case FormatJSONL:
+		enc = &jsonlEncoder{w: bw}
+	case FormatJSON:
+		enc = &jsonEncoder{w: bw}
+	case FormatCSV:
+		enc = &csvEncoder{w: csv.NewWriter(bw), columns: o.columns}
+	}
+
+	count := 0
+	it := b.IterCtx(ctx, q)
+	for it.Next() {
+		var item map[string]interface{}
+		if err := it.Scan(&item); err != nil {
+			return count, err
+		}
+		if err := enc.encode(item); err != nil {
+			return count, err
+		}
+		count++
+		if it.endOfPage() {
+			if err := enc.endPage(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return count, err
+	}
+	if err := enc.close(); err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// encodes items in a format
+type itemEncoder interface {
+	encode(item map[string]interface{}) error
+	// called after the last item of every fetched page
+	endPage() error
+	close() error
+}
+
+type jsonlEncoder struct {
+	w io.Writer
+}
+
+func (e *jsonlEncoder) encode(item map[string]interface{}) error {
+	return json.NewEncoder(e.w).Encode(item)
+}
+
+func (e *jsonlEncoder) endPage() error {
+	return nil
+}
+
+func (e *jsonlEncoder) close() error {
+	return nil
+}
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) encode(item map[string]interface{}) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) endPage() error {
+	return nil
+}
+
+func (e *jsonEncoder) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// column of the fields of an item that are not columns, as a json object
+const csvExtraColumn = "__extra"
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []string
+	// the columns are inferred from the first page, other fields are written to csvExtraColumn
+	inferred bool
+	// items of the first page buffered until its end if no columns are set
+	items  []map[string]interface{}
+	header bool
+}
+
+func (e *csvEncoder) encode(item map[string]interface{}) error {
+	if e.columns == nil {
+		e.items = append(e.items, item)
+		return nil
+	}
+	return e.write(item)
+}
+
+// infers the columns from the buffered items of the first page and writes them
+func (e *csvEncoder) endPage() error {
+	if e.columns != nil {
+		return nil
+	}
+	e.columns = csvColumns(e.items)
+	e.inferred = true
+	for _, item := range e.items {
+		if err := e.write(item); err != nil {
+			return err
+		}
+	}
+	e.items = nil
+	return nil
+}
+
+// writes the header once
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	header := e.columns
+	if e.inferred {
+		header = append(append([]string{}, e.columns...), csvExtraColumn)
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) write(item map[string]interface{}) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(e.columns), len(e.columns)+1)
+	written := 0
+	for n, column := range e.columns {
+		value, ok := item[column]
+		if !ok {
+			continue
+		}
+		written++
+		cell, err := csvCell(column, value)
+		if err != nil {
+			return err
+		}
+		record[n] = cell
+	}
+	if e.inferred {
+		extra := ""
+		if written < len(item) {
+			fields := make(map[string]interface{})
+			for field, value := range item {
+				if !contains(e.columns, field) {
+					fields[field] = value
+				}
+			}
+			data, err := json.Marshal(fields)
+			if err != nil {
+				return err
+			}
+			extra = string(data)
+		}
+		record = append(record, extra)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) close() error {
+	if err := e.endPage(); err != nil {
+		return err
+	}
+	// write the header even without items
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// reports whether the values contain the value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the fields of the items, the key first and the other fields sorted
+func csvColumns(items []map[string]interface{}) []string {
+	fields := make(map[string]bool)
+	for _, item := range items {
+		for field := range item {
+			if field != "key" {
+				fields[field] = true
+			}
+		}
+	}
+	columns := make([]string, 0, len(fields)+1)
+	for field := range fields {
+		columns = append(columns, field)
+	}
+	sort.Strings(columns)
+	return append([]string{"key"}, columns...)
+}
+
+// encodes the value of a field as a csv cell
+func csvCell(field string, value interface{}) (string, error) {
+	if s, ok := value.(string); ok && (field == "key" || (s != "" && !json.Valid([]byte(s)))) {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodes a csv cell of a field
+func csvValue(field, cell string) (interface{}, error) {
+	if field == "key" || !json.Valid([]byte(cell)) {
+		return cell, nil
+	}
+	return decodeJSON([]byte(cell))
+}
+
+// decodes json
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// options of an Import operation
+type importOptions struct {
+	regenerateKeys bool
+	failedRetries  int
+	progress       func(ImportProgress)
+}
+
+// ImportOption is a functional option for an Import operation
+type ImportOption func(*importOptions)
+
+// WithRegenerateKeys option for dropping the keys of the imported items, so that new keys are generated
+func WithRegenerateKeys() ImportOption {
+	return func(o *importOptions) {
+		o.regenerateKeys = true
+	}
+}
+
+// WithImportFailedRetries option for retrying the items that failed to be put, up to retries times
+func WithImportFailedRetries(retries int) ImportOption {
+	return func(o *importOptions) {
+		o.failedRetries = retries
+	}
+}
+
+// WithImportProgress option for setting a function called with the progress after each put request
+func WithImportProgress(f func(ImportProgress)) ImportOption {
+	return func(o *importOptions) {
+		o.progress = f
+	}
+}
+
+// ImportProgress progress of an Import operation
+type ImportProgress struct {
+	// number of items put in the database so far
+	Processed int
+	// number of items that failed to be put so far
+	Failed int
+}
+
+// ImportOutput output of an Import operation
+type ImportOutput struct {
+	// number of items put in the database
+	Processed int
+	// items that failed to be put in the database
+	Failed []*FailedItem
+}
+
+// Import puts the items read from r in the format in the database.
+//
+// Items are put in requests of at most 25 items as they are read. Items keep their keys unless
+// WithRegenerateKeys is set, items without a key get a generated key.
+// If some items failed, the output is returned together with a *PartialFailureError.
+// If reading the items or a request fails, the import stops and the output so far is returned
+// with the error.
+func (b *Base) Import(r io.Reader, format Format, opts ...ImportOption) (*ImportOutput, error) {
+	return b.ImportCtx(context.Background(), r, format, opts...)
+}
+
+// ImportCtx is like Import but uses the provided context for the requests.
+func (b *Base) ImportCtx(ctx context.Context, r io.Reader, format Format, opts ...ImportOption) (*ImportOutput, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	o := &importOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var dec itemDecoder
+	switch format {
+	case FormatJSONL:
+		dec = newJSONLDecoder(r)
+	case FormatJSON:
+		dec = newJSONDecoder(r)
+	case FormatCSV:
+		dec = newCSVDecoder(r)
+	}
+
+	out := &ImportOutput{}
+	batch := make([]map[string]interface{}, 0, maxPutItems)
+	put := func() error {
+		pm, err := b.PutManyCtx(ctx, batch, WithFailedRetries(o.failedRetries))
+		if pm != nil {
+			out.Processed += len(pm.Processed)
+			out.Failed = append(out.Failed, pm.Failed...)
+		}
+		var pfe *PartialFailureError
+		if err != nil && !errors.As(err, &pfe) {
+			return err
+		}
+		batch = batch[:0]
+		if o.progress != nil {
+			o.progress(ImportProgress{Processed: out.Processed, Failed: len(out.Failed)})
+		}
+		return nil
+	}
+
+	for n := 1; ; n++ {
+		item, err := dec.decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, fmt.Errorf("%w: item %d: %v", deta.ErrBadItem, n, err)
+		}
+		if o.regenerateKeys {
+			delete(item, "key")
+		}
+		batch = append(batch, item)
+		if len(batch) == maxPutItems {
+			if err := put(); err != nil {
+				return out, err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := put(); err != nil {
+			return out, err
+		}
+	}
+
+	if len(out.Failed) > 0 {
+		return out, &PartialFailureError{Failed: out.Failed}
+	}
+	return out, nil
+}
+
+// decodes items in a format, returns io.EOF after the last item
+type itemDecoder interface {
+	decode() (map[string]interface{}, error)
+}
+
+type jsonlDecoder struct {
+	dec *json.Decoder
+}
+
+func newJSONLDecoder(r io.Reader) *jsonlDecoder {
+	return &jsonlDecoder{dec: json.NewDecoder(r)}
+}
+
+func (d *jsonlDecoder) decode() (map[string]interface{}, error) {
+	var item map[string]interface{}
+	if err := d.dec.Decode(&item); err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("item is not an object")
+	}
+	return item, nil
+}
+
+type jsonDecoder struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func newJSONDecoder(r io.Reader) *jsonDecoder {
+	return &jsonDecoder{dec: json.NewDecoder(r)}
+}
+
+func (d *jsonDecoder) decode() (map[string]interface{}, error) {
+	if !d.started {
+		d.started = true
+		tok, err := d.dec.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tok != json.Delim('[') {
+			return nil, errors.New("items are not an array")
+		}
+	}
+	if !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	var item map[string]interface{}
+	if err := d.dec.Decode(&item); err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("item is not an object")
+	}
+	return item, nil
+}
+
+type csvDecoder struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVDecoder(r io.Reader) *csvDecoder {
+	return &csvDecoder{r: csv.NewReader(r)}
+}
+
+func (d *csvDecoder) decode() (map[string]interface{}, error) {
+	if d.header == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		d.header = header
+	}
+	record, err := d.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	item := make(map[string]interface{})
+	var extra map[string]interface{}
+	for n, cell := range record {
+		if cell == "" {
+			continue
+		}
+		if d.header[n] == csvExtraColumn {
+			if err := json.Unmarshal([]byte(cell), &extra); err != nil {
+				return nil, fmt.Errorf("bad %s column: %v", csvExtraColumn, err)
+			}
+			continue
+		}
+		value, err := csvValue(d.header[n], cell)
+		if err != nil {
+			return nil, err
+		}
+		item[d.header[n]] = value
+	}
+	for field, value := range extra {
+		if _, ok := item[field]; !ok {
+			item[field] = value
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,218 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// options of an Iterator
+type iterOptions struct {
+	pageSize int
+	limit    int
+	desc     bool
+	lastKey  string
+}
+
+// IterOption is a functional option for an Iterator
+type IterOption func(*iterOptions)
+
+// WithPageSize option for setting the maximum number of items fetched in a single request
+func WithPageSize(pageSize int) IterOption {
+	return func(o *iterOptions) {
+		o.pageSize = pageSize
+	}
+}
+
+// WithLimit option for setting the maximum number of items iterated over in total
+func WithLimit(limit int) IterOption {
+	return func(o *iterOptions) {
+		o.limit = limit
+	}
+}
+
+// WithDesc option for iterating over the items in descending order of their keys
+func WithDesc() IterOption {
+	return func(o *iterOptions) {
+		o.desc = true
+	}
+}
+
+// WithLastKey option for starting the iteration after the item with the key
+func WithLastKey(lastKey string) IterOption {
+	return func(o *iterOptions) {
+		o.lastKey = lastKey
+	}
+}
+
+// Iterator iterates over the items matching a query, transparently fetching every page.
+//
+//	it := users.Iter(base.Query{{"active": true}})
+//	for it.Next() {
+//		var u User
+//		if err := it.Scan(&u); err != nil {
+//			return err
+//		}
+//		fmt.Println(u.Username)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator struct {
+	ctx  context.Context
+	api  API
+	q    Query
+	opts iterOptions
+
+	page  []json.RawMessage
+	pos   int
+	cur   json.RawMessage
+	count int
+	done  bool
+	err   error
+}
+
+// NewIterator returns a pointer to a new Iterator over the items matching the query in the API.
+//
+// Pages are fetched with the FetchCtx method of the API.
+func NewIterator(ctx context.Context, api API, q Query, opts ...IterOption) *Iterator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	it := &Iterator{
+		ctx: ctx,
+		api: api,
+		q:   q,
+	}
+	for _, opt := range opts {
+		opt(&it.opts)
+	}
+	return it
+}
+
+// Iter returns a pointer to a new Iterator over the items matching the query.
+//
+// A nil query iterates over all items.
+func (b *Base) Iter(q Query, opts ...IterOption) *Iterator {
+	return b.IterCtx(context.Background(), q, opts...)
+}
+
+// IterCtx is like Iter but uses the provided context for the requests.
+func (b *Base) IterCtx(ctx context.Context, q Query, opts ...IterOption) *Iterator {
+	return NewIterator(ctx, b, q, opts...)
+}
+
+// FetchAll fetches all items matching the query from every page onto dest.
+//
+// The dest should be a pointer to a slice.
+func (b *Base) FetchAll(q Query, dest interface{}, opts ...IterOption) error {
+	return b.FetchAllCtx(context.Background(), q, dest, opts...)
+}
+
+// FetchAllCtx is like FetchAll but uses the provided context for the requests.
+func (b *Base) FetchAllCtx(ctx context.Context, q Query, dest interface{}, opts ...IterOption) error {
+	return b.IterCtx(ctx, q, opts...).ScanAll(dest)
+}
+
+// fetches the next page
+func (it *Iterator) fetchPage() error {
+	if err := it.ctx.Err(); err != nil {
+		return err
+	}
+
+	i := &FetchInput{
+		Q:       it.q,
+		Dest:    &it.page,
+		Limit:   it.opts.pageSize,
+		LastKey: it.opts.lastKey,
+		Desc:    it.opts.desc,
+	}
+	// do not fetch more items than left to the limit
+	if left := it.opts.limit - it.count; it.opts.limit > 0 && (i.Limit <= 0 || left < i.Limit) {
+		i.Limit = left
+	}
+
+	it.page = nil
+	lastKey, err := it.api.FetchCtx(it.ctx, i)
+	if err != nil {
+		return err
+	}
+	it.pos = 0
+	it.opts.lastKey = lastKey
+	it.done = lastKey == ""
+	return nil
+}
+
+// Next advances the iterator to the next item.
+//
+// Returns false when there are no more items or an error occurred, check Err for the error.
+func (it *Iterator) Next() bool {
+	if it.err != nil || (it.opts.limit > 0 && it.count >= it.opts.limit) {
+		return false
+	}
+	for it.pos >= len(it.page) {
+		if it.done {
+			return false
+		}
+		if err := it.fetchPage(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	it.cur = it.page[it.pos]
+	it.pos++
+	it.count++
+	return true
+}
+
+// reports whether the current item is the last item of its fetched page
+func (it *Iterator) endOfPage() bool {
+	return it.cur != nil && it.pos == len(it.page)
+}
+
+// Scan scans the current item onto dest.
+func (it *Iterator) Scan(dest interface{}) error {
+	if it.cur == nil {
+		return fmt.Errorf("%w: no current item, call Next first", deta.ErrBadDestination)
+	}
+	if err := json.Unmarshal(it.cur, dest); err != nil {
+		return fmt.Errorf("%w: %v", deta.ErrBadDestination, err)
+	}
+	return nil
+}
+
+// ScanAll scans all remaining items onto dest.
+//
+// The dest should be a pointer to a slice.
+func (it *Iterator) ScanAll(dest interface{}) error {
+	items := make([]json.RawMessage, 0)
+	for it.Next() {
+		items = append(items, it.cur)
+	}
+	if it.err != nil {
+		return it.err
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("%w: %v", deta.ErrBadDestination, err)
+	}
+	return nil
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// LastKey returns the key of the last fetched page.
+//
+// Provide it with WithLastKey to resume iterating after the last fetched page.
+func (it *Iterator) LastKey() string {
+	return it.opts.lastKey
+}
@@ -0,0 +1,295 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/internal/query"
+)
+
+// query operator suffixes
+const (
+	opNe          = "?" + query.OpNe
+	opLt          = "?" + query.OpLt
+	opGt          = "?" + query.OpGt
+	opLte         = "?" + query.OpLte
+	opGte         = "?" + query.OpGte
+	opPfx         = "?" + query.OpPfx
+	opRange       = "?" + query.OpRange
+	opContains    = "?" + query.OpContains
+	opNotContains = "?" + query.OpNotContains
+)
+
+// Match reports whether the item matches the query, without a request to the Base.
+//
+// The item, a struct or a map, is matched as it would be stored in the Base. Maps in the query are
+// ORed, the conditions in a map are ANDed and nested fields are separated by dots. An empty query
+// matches every item.
+//
+//	q := base.Query{
+//		{"active": true, "address.city": "berlin"},
+//		{"age?lt": 32},
+//	}
+//	ok, err := q.Match(&User{Active: true, Age: 20})
+func (q Query) Match(item interface{}) (bool, error) {
+	var nq []map[string]interface{}
+	if err := normalize(q, &nq); err != nil {
+		return false, fmt.Errorf("%w: %v", deta.ErrBadQuery, err)
+	}
+	if err := query.Validate(nq); err != nil {
+		return false, fmt.Errorf("%w: %v", deta.ErrBadQuery, err)
+	}
+	var ni map[string]interface{}
+	if err := normalize(item, &ni); err != nil || ni == nil {
+		return false, fmt.Errorf("%w: item is not an object", deta.ErrBadItem)
+	}
+	return query.Match(nq, ni), nil
+}
+
+// normalizes the value to its json representation onto dest
+func normalize(value interface{}, dest interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// QueryBuilder builds a Query, validating the operands of the conditions locally.
+//
+// Conditions joined with And are ANDed, queries joined with Or are ORed.
+//
+//	q, err := base.Where("age").Lt(32).And("active").Eq(true).
+//		Or(base.Where("name").Pfx("j")).
+//		Query()
+//
+// The example above builds a query for items where 'age' is less than 32 AND 'active' is true,
+// OR 'name' starts with "j".
+//
+// The zero value is an empty QueryBuilder ready to use.
+type QueryBuilder struct {
+	groups []map[string]interface{}
+	err    error
+}
+
+// Condition is a pending condition on a field of a QueryBuilder
+type Condition struct {
+	qb    *QueryBuilder
+	field string
+}
+
+// Where starts a new QueryBuilder with a condition on the field.
+//
+// Nested fields are separated by dots.
+func Where(field string) *Condition {
+	qb := &QueryBuilder{}
+	return qb.OrWhere(field)
+}
+
+// And adds a condition on the field that is ANDed with the previous conditions.
+//
+// After Or, the condition is ANDed with the conditions of the last ORed query only.
+func (qb *QueryBuilder) And(field string) *Condition {
+	return &Condition{qb: qb, field: field}
+}
+
+// OrWhere adds a condition on the field that is ORed with the previous conditions.
+func (qb *QueryBuilder) OrWhere(field string) *Condition {
+	qb.groups = append(qb.groups, make(map[string]interface{}))
+	return &Condition{qb: qb, field: field}
+}
+
+// Or ORs the conditions of the other queries with the previous conditions.
+func (qb *QueryBuilder) Or(others ...*QueryBuilder) *QueryBuilder {
+	for _, other := range others {
+		if other.err != nil && qb.err == nil {
+			qb.err = other.err
+		}
+		for _, g := range other.groups {
+			cp := make(map[string]interface{}, len(g))
+			for k, v := range g {
+				cp[k] = v
+			}
+			qb.groups = append(qb.groups, cp)
+		}
+	}
+	return qb
+}
+
+// Query returns the built Query, or the first error of an invalid condition.
+//
+// The error matches deta.ErrBadQuery with errors.Is.
+func (qb *QueryBuilder) Query() (Query, error) {
+	if qb.err != nil {
+		return nil, qb.err
+	}
+	q := make(Query, len(qb.groups))
+	for n, g := range qb.groups {
+		q[n] = g
+	}
+	return q, nil
+}
+
+// adds the condition to the current group if the operand is valid
+func (c *Condition) add(op string, operand interface{}, validate func(interface{}) error) *QueryBuilder {
+	qb := c.qb
+	if qb.err != nil {
+		return qb
+	}
+
+	err := validateField(c.field)
+	if err == nil && validate != nil {
+		err = validate(operand)
+	}
+	if err == nil {
+		_, err = json.Marshal(operand)
+	}
+	if err != nil {
+		qb.err = fmt.Errorf("%w: condition '%s%s': %v", deta.ErrBadQuery, c.field, op, err)
+		return qb
+	}
+
+	if len(qb.groups) == 0 {
+		qb.groups = append(qb.groups, make(map[string]interface{}))
+	}
+	g := qb.groups[len(qb.groups)-1]
+	key := c.field + op
+	if _, ok := g[key]; ok {
+		qb.err = fmt.Errorf("%w: duplicate condition '%s'", deta.ErrBadQuery, key)
+		return qb
+	}
+	g[key] = operand
+	return qb
+}
+
+// Eq adds a condition that the field is equal to the value.
+func (c *Condition) Eq(value interface{}) *QueryBuilder {
+	return c.add("", value, nil)
+}
+
+// Ne adds a condition that the field is not equal to the value.
+func (c *Condition) Ne(value interface{}) *QueryBuilder {
+	return c.add(opNe, value, nil)
+}
+
+// Lt adds a condition that the field is less than the value.
+func (c *Condition) Lt(value interface{}) *QueryBuilder {
+	return c.add(opLt, value, validateComparable)
+}
+
+// Gt adds a condition that the field is greater than the value.
+func (c *Condition) Gt(value interface{}) *QueryBuilder {
+	return c.add(opGt, value, validateComparable)
+}
+
+// Lte adds a condition that the field is less than or equal to the value.
+func (c *Condition) Lte(value interface{}) *QueryBuilder {
+	return c.add(opLte, value, validateComparable)
+}
+
+// Gte adds a condition that the field is greater than or equal to the value.
+func (c *Condition) Gte(value interface{}) *QueryBuilder {
+	return c.add(opGte, value, validateComparable)
+}
+
+// Pfx adds a condition that the field starts with the prefix.
+func (c *Condition) Pfx(prefix string) *QueryBuilder {
+	return c.add(opPfx, prefix, nil)
+}
+
+// Range adds a condition that the field is between start and end, inclusive.
+func (c *Condition) Range(start, end interface{}) *QueryBuilder {
+	return c.add(opRange, []interface{}{start, end}, func(interface{}) error {
+		if err := validateComparable(start); err != nil {
+			return err
+		}
+		if err := validateComparable(end); err != nil {
+			return err
+		}
+		less, ok := lessThan(end, start)
+		if !ok {
+			return fmt.Errorf("start %v and end %v are not of the same type", start, end)
+		}
+		if less {
+			return fmt.Errorf("start %v is greater than end %v", start, end)
+		}
+		return nil
+	})
+}
+
+// Contains adds a condition that the field, a string or a list, contains the value.
+func (c *Condition) Contains(value interface{}) *QueryBuilder {
+	return c.add(opContains, value, validateNotNil)
+}
+
+// NotContains adds a condition that the field, a string or a list, does not contain the value.
+func (c *Condition) NotContains(value interface{}) *QueryBuilder {
+	return c.add(opNotContains, value, validateNotNil)
+}
+
+// validates the name of a field
+func validateField(field string) error {
+	if field == "" {
+		return fmt.Errorf("empty field")
+	}
+	if strings.Contains(field, "?") {
+		return fmt.Errorf("field contains '?'")
+	}
+	for _, part := range strings.Split(field, ".") {
+		if part == "" {
+			return fmt.Errorf("empty nested field")
+		}
+	}
+	return nil
+}
+
+// validates that the value is a number or a string
+func validateComparable(value interface{}) error {
+	if _, ok := toFloat(value); ok {
+		return nil
+	}
+	if _, ok := value.(string); ok {
+		return nil
+	}
+	return fmt.Errorf("%v is not a number or a string", value)
+}
+
+// validates that the value is not nil
+func validateNotNil(value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("value is nil")
+	}
+	return nil
+}
+
+// converts a number to a float64
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// reports whether a is less than b, if both are numbers or both are strings
+func lessThan(a, b interface{}) (bool, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return x < y, ok
+	}
+	x, ok := a.(string)
+	if !ok {
+		return false, false
+	}
+	y, ok := b.(string)
+	return x < y, ok
+}
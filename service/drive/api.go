@@ -0,0 +1,73 @@
+package drive
+
+import (
+	"context"
+	"io"
+)
+
+// API is the interface of a Deta Drive service client, implemented by Drive
+//
+// Use it to mock a Drive in tests, see package drivemock, or to decorate a Drive with additional behavior.
+type API interface {
+	Get(name string) (io.ReadCloser, error)
+	GetCtx(ctx context.Context, name string) (io.ReadCloser, error)
+	GetWithProgress(name string, fn ProgressFunc) (io.ReadCloser, error)
+	GetWithProgressCtx(ctx context.Context, name string, fn ProgressFunc) (io.ReadCloser, error)
+	GetRange(name string, offset, length int64) (io.ReadCloser, error)
+	GetRangeCtx(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error)
+	Open(name string) (FileReader, error)
+	OpenCtx(ctx context.Context, name string) (FileReader, error)
+	Stat(name string) (*FileInfo, error)
+	StatCtx(ctx context.Context, name string) (*FileInfo, error)
+	Exists(name string) (bool, error)
+	ExistsCtx(ctx context.Context, name string) (bool, error)
+	Put(i *PutInput) (string, error)
+	PutCtx(ctx context.Context, i *PutInput) (string, error)
+	StartUpload(name, contentType string) (*UploadSession, error)
+	StartUploadCtx(ctx context.Context, name, contentType string) (*UploadSession, error)
+	Upload(i *UploadInput) (string, error)
+	UploadCtx(ctx context.Context, i *UploadInput) (string, error)
+	AbortUpload(s *UploadSession) error
+	AbortUploadCtx(ctx context.Context, s *UploadSession) error
+	Create(name, contentType string) (FileWriter, error)
+	CreateCtx(ctx context.Context, name, contentType string) (FileWriter, error)
+	List(limit int, prefix, last string) (*ListOutput, error)
+	ListCtx(ctx context.Context, limit int, prefix, last string) (*ListOutput, error)
+	DeleteMany(names []string) (*DeleteManyOutput, error)
+	DeleteManyCtx(ctx context.Context, names []string) (*DeleteManyOutput, error)
+	Delete(name string) (string, error)
+	DeleteCtx(ctx context.Context, name string) (string, error)
+	DeletePrefix(prefix string) (*DeleteManyOutput, error)
+	DeletePrefixCtx(ctx context.Context, prefix string) (*DeleteManyOutput, error)
+	Sync(i *SyncInput) (*SyncOutput, error)
+	SyncCtx(ctx context.Context, i *SyncInput) (*SyncOutput, error)
+	Copy(src, dst string) (string, error)
+	CopyCtx(ctx context.Context, src, dst string) (string, error)
+	CopyTo(to API, src, dst string) (string, error)
+	CopyToCtx(ctx context.Context, to API, src, dst string) (string, error)
+	Move(src, dst string) (string, error)
+	MoveCtx(ctx context.Context, src, dst string) (string, error)
+	CopyPrefix(i *CopyPrefixInput) (*CopyPrefixOutput, error)
+	CopyPrefixCtx(ctx context.Context, i *CopyPrefixInput) (*CopyPrefixOutput, error)
+}
+
+var _ API = (*Drive)(nil)
+
+// Decorator wraps an API with additional behavior, like caching, logging or metrics
+type Decorator func(API) API
+
+// Decorate returns the API wrapped with the decorators.
+//
+// The first decorator is the outermost, it handles calls first.
+//
+//	drawings, err := drive.New(d, "drawings")
+//	if err != nil {
+//		return err
+//	}
+//	api := drive.Decorate(drawings, withLogging, withMetrics)
+func Decorate(api API, decorators ...Decorator) API {
+	for n := len(decorators) - 1; n >= 0; n-- {
+		api = decorators[n](api)
+	}
+	return api
+}
@@ -0,0 +1,52 @@
+// Package basestore provides a drive.UploadStore storing upload sessions in a Deta Base.
+//
+//	sessions, err := base.New(d, "upload_sessions")
+//	if err != nil {
+//		return err
+//	}
+//	store := basestore.New(sessions)
+//	_, err = drawings.Upload(&drive.UploadInput{Session: s, Body: f, Store: store})
+package basestore
+
+import (
+	"github.com/deta/deta-go/service/base"
+	"github.com/deta/deta-go/service/drive"
+)
+
+// Store stores upload sessions as items in a Base, keyed by the name of their file
+type Store struct {
+	base base.API
+}
+
+var _ drive.UploadStore = (*Store)(nil)
+
+// New returns a pointer to a new Store storing the sessions in the Base
+func New(b base.API) *Store {
+	return &Store{base: b}
+}
+
+// an upload session stored in a Base
+type item struct {
+	Key string `json:"key"`
+	*drive.UploadSession
+}
+
+// Save puts the session in the Base
+func (s *Store) Save(session *drive.UploadSession) error {
+	_, err := s.base.Put(&item{Key: session.Name, UploadSession: session})
+	return err
+}
+
+// Load gets the session from the Base
+func (s *Store) Load(name string) (*drive.UploadSession, error) {
+	i := &item{UploadSession: &drive.UploadSession{}}
+	if err := s.base.Get(name, i); err != nil {
+		return nil, err
+	}
+	return i.UploadSession, nil
+}
+
+// Delete deletes the session from the Base
+func (s *Store) Delete(name string) error {
+	return s.base.Delete(name)
+}
@@ -0,0 +1,49 @@
+package basestore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
+	"github.com/deta/deta-go/service/base"
+	"github.com/deta/deta-go/service/drive"
+)
+
+func TestStore(t *testing.T) {
+	srv := detatest.NewServer()
+	defer srv.Close()
+	d, _ := srv.Deta()
+	sessions, err := base.New(d, "upload_sessions")
+	if err != nil {
+		t.Fatalf("Failed to create base: %v", err)
+	}
+	store := New(sessions)
+
+	s := &drive.UploadSession{
+		Name:        "dir/file.txt",
+		UploadID:    "upload_id",
+		ContentType: "text/plain",
+		PartSize:    1024,
+		Completed:   []int{1, 2},
+	}
+	if err := store.Save(s); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	loaded, err := store.Load(s.Name)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if loaded.Name != s.Name || loaded.UploadID != s.UploadID || loaded.PartSize != s.PartSize ||
+		!reflect.DeepEqual(loaded.Completed, s.Completed) {
+		t.Errorf("Loaded session not as expected. Expected: %+v Got: %+v", s, loaded)
+	}
+
+	if err := store.Delete(s.Name); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if _, err := store.Load(s.Name); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+}
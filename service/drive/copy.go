@@ -0,0 +1,196 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// default number of files copied concurrently
+const defaultCopyConcurrency = 4
+
+// Copy copies a file to another name in the Drive.
+//
+// The file is streamed from the download to the upload of the copy, in parts of 10 MiB,
+// and keeps its content type. An existing file with the name of the copy is overwritten.
+// Returns the name of the copy.
+func (d *Drive) Copy(src, dst string) (string, error) {
+	return d.CopyCtx(context.Background(), src, dst)
+}
+
+// CopyCtx is like Copy but uses the provided context for the requests.
+func (d *Drive) CopyCtx(ctx context.Context, src, dst string) (string, error) {
+	return d.CopyToCtx(ctx, d, src, dst)
+}
+
+// CopyTo copies a file of the Drive to a name in another Drive.
+//
+// The copy is like Copy, to is the Drive the copy is uploaded to.
+func (d *Drive) CopyTo(to API, src, dst string) (string, error) {
+	return d.CopyToCtx(context.Background(), to, src, dst)
+}
+
+// CopyToCtx is like CopyTo but uses the provided context for the requests.
+func (d *Drive) CopyToCtx(ctx context.Context, to API, src, dst string) (string, error) {
+	if src == "" || dst == "" {
+		return "", deta.ErrEmptyName
+	}
+	if to == nil {
+		return "", fmt.Errorf("%w: no destination drive", deta.ErrBadCopyInput)
+	}
+
+	o, err := d.get(ctx, src, nil)
+	if err != nil {
+		return "", err
+	}
+	defer o.BodyReadCloser.Close()
+
+	return to.PutCtx(ctx, &PutInput{
+		Name:        dst,
+		Body:        o.BodyReadCloser,
+		ContentType: o.Header.Get("Content-Type"),
+	})
+}
+
+// Move moves a file to another name in the Drive.
+//
+// The file is copied like in Copy and deleted once the copy is complete. If deleting the file
+// fails, the name of the copy is returned with the error.
+func (d *Drive) Move(src, dst string) (string, error) {
+	return d.MoveCtx(context.Background(), src, dst)
+}
+
+// MoveCtx is like Move but uses the provided context for the requests.
+func (d *Drive) MoveCtx(ctx context.Context, src, dst string) (string, error) {
+	if src == dst && src != "" {
+		// deleting the source would delete the file
+		if _, err := d.StatCtx(ctx, src); err != nil {
+			return "", err
+		}
+		return dst, nil
+	}
+	name, err := d.CopyCtx(ctx, src, dst)
+	if err != nil {
+		return "", err
+	}
+	if _, err := d.DeleteCtx(ctx, src); err != nil {
+		return name, err
+	}
+	return name, nil
+}
+
+// CopyPrefixInput input for CopyPrefix operation.
+type CopyPrefixInput struct {
+	// prefix of the names of the files to copy, all files if empty
+	Src string
+	// prefix replacing Src in the names of the copies
+	Dst string
+	// drive the files are copied to, the Drive itself if nil
+	To API
+	// maximum number of files copied concurrently, 4 if not set
+	Concurrency int
+}
+
+// CopyPrefixOutput output for CopyPrefix operation.
+type CopyPrefixOutput struct {
+	// names of the copies
+	Copied []string
+	// errors copying files, by name of the source file
+	Failed map[string]error
+}
+
+// CopyPrefix copies all files with a prefix in their names.
+//
+// Every file is copied like in Copy, with the prefix of its name replaced.
+// If some files failed to be copied, the output is returned together with an error wrapping
+// the error of the first failed file.
+func (d *Drive) CopyPrefix(i *CopyPrefixInput) (*CopyPrefixOutput, error) {
+	return d.CopyPrefixCtx(context.Background(), i)
+}
+
+// CopyPrefixCtx is like CopyPrefix but uses the provided context for the requests.
+func (d *Drive) CopyPrefixCtx(ctx context.Context, i *CopyPrefixInput) (*CopyPrefixOutput, error) {
+	var to API = d
+	if i.To != nil {
+		to = i.To
+	}
+	if to == API(d) && i.Src == i.Dst {
+		return nil, fmt.Errorf("%w: source and destination prefix are the same", deta.ErrBadCopyInput)
+	}
+
+	// list every file first, so that copies with the source prefix are not copied again
+	names, err := d.listNames(ctx, i.Src)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := i.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultCopyConcurrency
+	}
+	out := &CopyPrefixOutput{
+		Copied: make([]string, 0, len(names)),
+		Failed: make(map[string]error),
+	}
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for n, name := range names {
+		// wait for a free slot, do not start new copies once the context is done
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			for _, name := range names[n:] {
+				out.Failed[name] = err
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			copied, err := d.CopyToCtx(ctx, to, name, i.Dst+strings.TrimPrefix(name, i.Src))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				out.Failed[name] = err
+				return
+			}
+			out.Copied = append(out.Copied, copied)
+		}(name)
+	}
+	wg.Wait()
+	sort.Strings(out.Copied)
+
+	if len(out.Failed) > 0 {
+		failed := make([]string, 0, len(out.Failed))
+		for name := range out.Failed {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+		return out, fmt.Errorf("%d file(s) failed to be copied: %w", len(failed), out.Failed[failed[0]])
+	}
+	return out, nil
+}
+
+// lists the names of all files with the prefix
+func (d *Drive) listNames(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := d.listPages(ctx, prefix, func(page []string) error {
+		names = append(names, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -2,8 +2,8 @@
 package drive
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,6 +16,10 @@ import (
 const (
 	uploadChunkSize = 1024 * 1024 * 10
 	driveEndpoint   = "https://drive.deta.sh/v1"
+	// maximum number of names in a delete request
+	maxDeleteNames = 1000
+	// maximum number of names in a page of a list request
+	maxListLimit = 1000
 )
 
 // Drive is a Deta Drive service client that offers the API to make requests to Deta Drive
@@ -36,7 +40,10 @@ func New(d *deta.Deta, driveName string) (*Drive, error) {
 	parts := strings.Split(projectKey, "_")
 	projectID := parts[0]
 
-	rootEndpoint := os.Getenv("DETA_DRIVE_ROOT_ENDPOINT")
+	rootEndpoint := d.DriveRootEndpoint
+	if rootEndpoint == "" {
+		rootEndpoint = os.Getenv("DETA_DRIVE_ROOT_ENDPOINT")
+	}
 	if rootEndpoint == "" {
 		rootEndpoint = driveEndpoint
 	}
@@ -46,7 +53,7 @@ func New(d *deta.Deta, driveName string) (*Drive, error) {
 			AuthType:    "api-key",
 			HeaderKey:   "X-API-Key",
 			HeaderValue: projectKey,
-		}),
+		}, d.HTTPClient, d.RetryPolicy),
 	}, nil
 }
 
@@ -54,6 +61,22 @@ func New(d *deta.Deta, driveName string) (*Drive, error) {
 //
 // Returns a io.ReadCloser for the file.
 func (d *Drive) Get(name string) (io.ReadCloser, error) {
+	return d.GetCtx(context.Background(), name)
+}
+
+// GetCtx is like Get but uses the provided context for the request.
+//
+// The context also governs reading from the returned io.ReadCloser.
+func (d *Drive) GetCtx(ctx context.Context, name string) (io.ReadCloser, error) {
+	o, err := d.get(ctx, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o.BodyReadCloser, nil
+}
+
+// Downloads a file with the headers, the body of the output is not read.
+func (d *Drive) get(ctx context.Context, name string, headers map[string]string) (*client.RequestOutput, error) {
 	if name == "" {
 		return nil, deta.ErrEmptyName
 	}
@@ -61,16 +84,19 @@ func (d *Drive) Get(name string) (io.ReadCloser, error) {
 	url := "/files/download"
 	queryParams := map[string]string{"name": name}
 	o, err := d.client.Request(&client.RequestInput{
+		Context:          ctx,
 		Path:             url,
 		QueryParams:      queryParams,
 		Method:           "GET",
+		Headers:          headers,
+		Idempotent:       true,
 		ReturnReadCloser: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return o.BodyReadCloser, nil
+	return o, nil
 }
 
 // startUploadResponse response for startUpload operation
@@ -84,10 +110,11 @@ type startUploadResponse struct {
 // Initializes a chuncked file upload.
 //
 // If successful, returns the UploadID
-func (d *Drive) startUpload(name string) (string, error) {
+func (d *Drive) startUpload(ctx context.Context, name string) (string, error) {
 	url := "/uploads"
 	queryParams := map[string]string{"name": name}
 	o, err := d.client.Request(&client.RequestInput{
+		Context:     ctx,
 		Path:        url,
 		QueryParams: queryParams,
 		Method:      "POST",
@@ -106,10 +133,11 @@ func (d *Drive) startUpload(name string) (string, error) {
 }
 
 // End a chuncked upload.
-func (d *Drive) finishUpload(name, uploadId string) error {
+func (d *Drive) finishUpload(ctx context.Context, name, uploadId string) error {
 	url := fmt.Sprintf("/uploads/%s", uploadId)
 	queryParams := map[string]string{"name": name}
 	_, err := d.client.Request(&client.RequestInput{
+		Context:     ctx,
 		Path:        url,
 		QueryParams: queryParams,
 		Method:      "PATCH",
@@ -118,25 +146,37 @@ func (d *Drive) finishUpload(name, uploadId string) error {
 }
 
 // Abort a chunked upload.
+//
+// The abort request is not bound to the context of the upload, so that an
+// upload cancelled through its context is still cleaned up.
 func (d *Drive) abortUpload(name, uploadId string) error {
+	return d.abortUploadCtx(context.Background(), name, uploadId)
+}
+
+// Abort a chunked upload with the provided context.
+func (d *Drive) abortUploadCtx(ctx context.Context, name, uploadId string) error {
 	url := fmt.Sprintf("/uploads/%s", uploadId)
 	queryParams := map[string]string{"name": name}
 	_, err := d.client.Request(&client.RequestInput{
+		Context:     ctx,
 		Path:        url,
 		QueryParams: queryParams,
 		Method:      "DELETE",
+		Idempotent:  true,
 	})
 	return err
 }
 
 // Uploads a chunked part.
-func (d *Drive) uploadPart(name string, chunk []byte, uploadId string, part int, contentType string) error {
+func (d *Drive) uploadPart(ctx context.Context, name string, chunk []byte, uploadId string, part int, contentType string) error {
 	url := fmt.Sprintf("/uploads/%s/parts", uploadId)
 	queryParams := map[string]string{"name": name, "part": fmt.Sprintf("%d", part)}
 	_, err := d.client.Request(&client.RequestInput{
+		Context:     ctx,
 		Path:        url,
 		QueryParams: queryParams,
 		Method:      "POST",
+		Idempotent:  true,
 		RawBody:     chunk,
 		ContentType: contentType,
 	})
@@ -156,12 +196,30 @@ type PutInput struct {
 	Body io.Reader
 	// content type of file
 	ContentType string
+	// maximum number of parts uploaded concurrently, parts are uploaded one after another if not set
+	//
+	// Each part in flight holds up to 10 MiB in memory.
+	Concurrency int
+	// number of times a failed part is retried, failed parts are not retried if not set
+	PartRetries int
+	// called after each uploaded part with the progress of the upload, if set
+	OnProgress ProgressFunc
 }
 
 // Put a file in the Drive.
 //
 // Returns the name of file that was put in the drive.
 func (d *Drive) Put(i *PutInput) (string, error) {
+	return d.PutCtx(context.Background(), i)
+}
+
+// PutCtx is like Put but uses the provided context for the requests.
+//
+// The file is uploaded in parts of 10 MiB, up to Concurrency parts at once. The upload is only
+// finished once every part has been uploaded. If a part fails after its retries or the context is
+// cancelled while the file is being uploaded, the upload is aborted. If aborting fails too, an
+// *AbortError with both errors is returned.
+func (d *Drive) PutCtx(ctx context.Context, i *PutInput) (string, error) {
 	if i.Name == "" {
 		return "", deta.ErrEmptyName
 	}
@@ -171,34 +229,75 @@ func (d *Drive) Put(i *PutInput) (string, error) {
 	}
 
 	// start upload
-	uploadId, err := d.startUpload(i.Name)
+	uploadId, err := d.startUpload(ctx, i.Name)
 	if err != nil {
 		return "", err
 	}
-	contentStream := i.Body
-	part := 1
 
-	for {
-		chunk := make([]byte, uploadChunkSize)
-		n, err := contentStream.Read(chunk)
-		chunk = chunk[:n]
-
-		if err == io.EOF {
-			err = d.finishUpload(i.Name, uploadId)
-			if err != nil {
-				return "", err
+	var progress *progressTracker
+	if i.OnProgress != nil {
+		progress = newProgressTracker(i.OnProgress, 0, readerSize(i.Body))
+	}
+
+	part := 0
+	eof := false
+	err = d.uploadParts(ctx, &partUpload{
+		name:        i.Name,
+		uploadId:    uploadId,
+		contentType: i.ContentType,
+		concurrency: i.Concurrency,
+		retries:     i.PartRetries,
+		progress:    progress,
+		next: func() (uploadJob, error) {
+			if eof {
+				return uploadJob{}, io.EOF
+			}
+			// fill complete parts even if the body returns short reads
+			chunk := make([]byte, uploadChunkSize)
+			n, err := io.ReadFull(i.Body, chunk)
+			switch {
+			case err == io.ErrUnexpectedEOF:
+				// the trailing part
+				eof = true
+			case err == io.EOF && part == 0:
+				// an empty file is uploaded as a single empty part
+				eof = true
+			case err != nil:
+				return uploadJob{}, err
 			}
+			part++
+			return uploadJob{part: part, chunk: chunk[:n]}, nil
+		},
+	})
+	if err == nil {
+		err = d.finishUpload(ctx, i.Name, uploadId)
+		if err == nil {
 			return i.Name, nil
 		}
+	}
+	if abortErr := d.abortUpload(i.Name, uploadId); abortErr != nil {
+		return "", &AbortError{Err: err, AbortErr: abortErr}
+	}
+	return "", err
+}
 
-		err = d.uploadPart(i.Name, chunk, uploadId, part, i.ContentType)
-		part = part + 1
+// AbortError is the error of a failed upload that also failed to be aborted
+//
+// An AbortError matches the error of the upload with errors.Is.
+type AbortError struct {
+	// error of the upload
+	Err error
+	// error aborting the upload
+	AbortErr error
+}
 
-		if err != nil {
-			err = d.abortUpload(i.Name, uploadId)
-			return "", err
-		}
-	}
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("%v (failed to abort upload: %v)", e.Err, e.AbortErr)
+}
+
+// Unwrap returns the error of the upload
+func (e *AbortError) Unwrap() error {
+	return e.Err
 }
 
 type paging struct {
@@ -219,6 +318,11 @@ type ListOutput struct {
 // List is paginated, returns the last name fetched, and the size if further pages are left.
 // Provide the last name in the subsequent list operation to list remaining pages.
 func (d *Drive) List(limit int, prefix, last string) (*ListOutput, error) {
+	return d.ListCtx(context.Background(), limit, prefix, last)
+}
+
+// ListCtx is like List but uses the provided context for the request.
+func (d *Drive) ListCtx(ctx context.Context, limit int, prefix, last string) (*ListOutput, error) {
 	url := "/files"
 	queryParams := make(map[string]string)
 	queryParams["limit"] = fmt.Sprintf("%d", limit)
@@ -229,9 +333,11 @@ func (d *Drive) List(limit int, prefix, last string) (*ListOutput, error) {
 		queryParams["last"] = last
 	}
 	o, err := d.client.Request(&client.RequestInput{
+		Context:     ctx,
 		Path:        url,
 		QueryParams: queryParams,
 		Method:      "GET",
+		Idempotent:  true,
 	})
 	if err != nil {
 		return nil, err
@@ -259,20 +365,48 @@ type DeleteManyOutput struct {
 
 // DeleteMany deletes multiple files in a Drive.
 //
-// Deletes at most 1000 files in a single request.
+// The names are deleted in requests of at most 1000 names.
 // The file names should be a string slice.
-// Returns a pointer to DeleteManyOutput.
+// Returns a pointer to DeleteManyOutput with the results of all requests. If a request fails,
+// the output of the previous requests is returned with the error.
 func (d *Drive) DeleteMany(names []string) (*DeleteManyOutput, error) {
+	return d.DeleteManyCtx(context.Background(), names)
+}
+
+// DeleteManyCtx is like DeleteMany but uses the provided context for the requests.
+func (d *Drive) DeleteManyCtx(ctx context.Context, names []string) (*DeleteManyOutput, error) {
 	if len(names) == 0 {
 		return nil, deta.ErrEmptyNames
 	}
 
-	if len(names) > 1000 {
-		return nil, errors.New("more than 1000 files to delete")
+	out := &DeleteManyOutput{
+		Deleted: make([]string, 0, len(names)),
+		Failed:  make(map[string]string),
+	}
+	for start := 0; start < len(names); start += maxDeleteNames {
+		end := start + maxDeleteNames
+		if end > len(names) {
+			end = len(names)
+		}
+		dr, err := d.deleteMany(ctx, names[start:end])
+		if err != nil {
+			return out, err
+		}
+		out.Deleted = append(out.Deleted, dr.Deleted...)
+		for name, msg := range dr.Failed {
+			out.Failed[name] = msg
+		}
 	}
+	return out, nil
+}
+
+// Deletes the files in a single request, of at most 1000 names.
+func (d *Drive) deleteMany(ctx context.Context, names []string) (*DeleteManyOutput, error) {
 	o, err := d.client.Request(&client.RequestInput{
-		Path:   "/files",
-		Method: "DELETE",
+		Context:    ctx,
+		Path:       "/files",
+		Method:     "DELETE",
+		Idempotent: true,
 		Body: &deleteManyRequest{
 			Names: names,
 		},
@@ -291,15 +425,74 @@ func (d *Drive) DeleteMany(names []string) (*DeleteManyOutput, error) {
 	return &dr, nil
 }
 
+// lists the names of the files with the prefix page by page, calling fn with the names of
+// every page until it returns an error
+func (d *Drive) listPages(ctx context.Context, prefix string, fn func(names []string) error) error {
+	last := ""
+	for {
+		lr, err := d.ListCtx(ctx, maxListLimit, prefix, last)
+		if err != nil {
+			return err
+		}
+		if err := fn(lr.Names); err != nil {
+			return err
+		}
+		if lr.Paging == nil || lr.Paging.Last == nil || *lr.Paging.Last == "" {
+			return nil
+		}
+		last = *lr.Paging.Last
+	}
+}
+
+// DeletePrefix deletes all files with a prefix in their names.
+//
+// The files are listed and deleted a page at a time. The prefix must not be empty.
+// Returns a pointer to DeleteManyOutput with the results of all pages. If a request fails,
+// the output of the previous pages is returned with the error.
+func (d *Drive) DeletePrefix(prefix string) (*DeleteManyOutput, error) {
+	return d.DeletePrefixCtx(context.Background(), prefix)
+}
+
+// DeletePrefixCtx is like DeletePrefix but uses the provided context for the requests.
+func (d *Drive) DeletePrefixCtx(ctx context.Context, prefix string) (*DeleteManyOutput, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("%w: empty prefix", deta.ErrEmptyName)
+	}
+
+	out := &DeleteManyOutput{
+		Deleted: make([]string, 0),
+		Failed:  make(map[string]string),
+	}
+	err := d.listPages(ctx, prefix, func(names []string) error {
+		if len(names) == 0 {
+			return nil
+		}
+		dr, err := d.DeleteManyCtx(ctx, names)
+		if dr != nil {
+			out.Deleted = append(out.Deleted, dr.Deleted...)
+			for name, msg := range dr.Failed {
+				out.Failed[name] = msg
+			}
+		}
+		return err
+	})
+	return out, err
+}
+
 // Delete a file from a Drive.
 //
 // Returns name of file deleted (even if the file does not exist)
 func (d *Drive) Delete(name string) (string, error) {
+	return d.DeleteCtx(context.Background(), name)
+}
+
+// DeleteCtx is like Delete but uses the provided context for the request.
+func (d *Drive) DeleteCtx(ctx context.Context, name string) (string, error) {
 	if name == "" {
 		return "", deta.ErrEmptyName
 	}
 	payload := []string{name}
-	dr, err := d.DeleteMany(payload)
+	dr, err := d.DeleteManyCtx(ctx, payload)
 	if err != nil {
 		return "", err
 	}
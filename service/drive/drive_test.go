@@ -2,23 +2,51 @@ package drive
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/deta/detatest"
 )
 
 const (
 	readChunkSize = 1024 * 1024 * 10
 )
 
+// fake deta server used if no test project key is provided
+var testServer *detatest.Server
+
+func TestMain(m *testing.M) {
+	if os.Getenv("DETA_SDK_TEST_PROJECT_KEY") == "" {
+		testServer = detatest.NewServer()
+	}
+	code := m.Run()
+	if testServer != nil {
+		testServer.Close()
+	}
+	os.Exit(code)
+}
+
 func SetupDrive() *Drive {
+	if testServer != nil {
+		d, _ := testServer.Deta()
+		drive, _ := New(d, "test_drive")
+		return drive
+	}
 	projectKey := os.Getenv("DETA_SDK_TEST_PROJECT_KEY")
 	driveName := os.Getenv("DETA_SDK_TEST_DRIVE_NAME")
 	d, _ := deta.New(deta.WithProjectKey(projectKey))
@@ -422,3 +450,1302 @@ func TestPutLargeFile(t *testing.T) {
 	}
 
 }
+
+func TestPutCtxCancelAbortsUpload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aborted := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/uploads"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"upload_id": "upload_id"}`))
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/parts"):
+			// cancel the upload while the part is in flight
+			ioutil.ReadAll(r.Body)
+			cancel()
+			<-r.Context().Done()
+		case r.Method == "DELETE" && strings.HasSuffix(r.URL.Path, "/uploads/upload_id"):
+			aborted <- struct{}{}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	os.Setenv("DETA_DRIVE_ROOT_ENDPOINT", srv.URL)
+	defer os.Unsetenv("DETA_DRIVE_ROOT_ENDPOINT")
+
+	d, _ := deta.New(deta.WithProjectKey("project_key"))
+	drive, _ := New(d, "drive")
+
+	_, err := drive.PutCtx(ctx, &PutInput{
+		Name: "cancelled.txt",
+		Body: strings.NewReader("cancelled"),
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.Canceled, err)
+	}
+
+	select {
+	case <-aborted:
+	default:
+		t.Errorf("Cancelled upload was not aborted")
+	}
+}
+
+func TestPutConcurrent(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	b := make([]byte, readChunkSize*3+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	name, err := drive.Put(&PutInput{
+		Name:        "concurrent_binary_file",
+		Body:        bytes.NewReader(b),
+		Concurrency: 3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to put file with error %v", err)
+	}
+
+	driveContent, err := drive.Get(name)
+	if err != nil {
+		t.Fatalf("Unexpected error while trying to get file content: %v", err)
+	}
+	defer driveContent.Close()
+
+	content, _ := ioutil.ReadAll(driveContent)
+	if !bytes.Equal(b, content) {
+		t.Errorf("Fetched content not equal to expected.")
+	}
+}
+
+func TestPutPartRetries(t *testing.T) {
+	if testServer == nil {
+		t.Skip("failing parts requires the fake server")
+	}
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	b := make([]byte, readChunkSize*2+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	// fail the second part of every upload once
+	failed := make(map[string]bool)
+	testServer.FailUploadPart = func(name string, part int) bool {
+		if part != 2 || failed[name] {
+			return false
+		}
+		failed[name] = true
+		return true
+	}
+	defer func() { testServer.FailUploadPart = nil }()
+
+	_, err := drive.Put(&PutInput{
+		Name:        "failed_part_file",
+		Body:        bytes.NewReader(b),
+		Concurrency: 2,
+	})
+	if !errors.Is(err, deta.ErrInternalServerError) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrInternalServerError, err)
+	}
+	if _, err := drive.Get("failed_part_file"); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("File of failed upload was not aborted. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+
+	name, err := drive.Put(&PutInput{
+		Name:        "retried_part_file",
+		Body:        bytes.NewReader(b),
+		Concurrency: 2,
+		PartRetries: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to put file with retried part: %v", err)
+	}
+	driveContent, err := drive.Get(name)
+	if err != nil {
+		t.Fatalf("Unexpected error while trying to get file content: %v", err)
+	}
+	defer driveContent.Close()
+
+	content, _ := ioutil.ReadAll(driveContent)
+	if !bytes.Equal(b, content) {
+		t.Errorf("Fetched content not equal to expected.")
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	if testServer == nil {
+		t.Skip("failing parts requires the fake server")
+	}
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	dir, err := ioutil.TempDir("", "deta-upload-sessions")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileUploadStore(dir)
+
+	b := make([]byte, readChunkSize*2+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	s, err := drive.StartUpload("resumed/file", "application/octet-stream")
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+
+	// fail the second part to interrupt the upload
+	testServer.FailUploadPart = func(name string, part int) bool {
+		return part == 2
+	}
+	_, err = drive.Upload(&UploadInput{Session: s, Body: bytes.NewReader(b), Store: store})
+	if !errors.Is(err, deta.ErrInternalServerError) {
+		t.Fatalf("Unexpected error value. Expected: %v Got: %v", deta.ErrInternalServerError, err)
+	}
+
+	loaded, err := store.Load("resumed/file")
+	if err != nil {
+		t.Fatalf("Failed to load upload session: %v", err)
+	}
+	if loaded.UploadID != s.UploadID || !reflect.DeepEqual(loaded.Completed, []int{1}) {
+		t.Errorf("Loaded session not as expected. Expected: %v %v Got: %v %v", s.UploadID, []int{1}, loaded.UploadID, loaded.Completed)
+	}
+
+	// resume the upload, only the missing parts are uploaded
+	var uploaded []int
+	testServer.FailUploadPart = func(name string, part int) bool {
+		uploaded = append(uploaded, part)
+		return false
+	}
+	defer func() { testServer.FailUploadPart = nil }()
+
+	name, err := drive.Upload(&UploadInput{
+		Session:     loaded,
+		Body:        io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))),
+		Store:       store,
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to resume upload: %v", err)
+	}
+	if len(uploaded) != 2 || uploaded[0]+uploaded[1] != 5 {
+		t.Errorf("Uploaded parts not as expected. Expected: %v Got: %v", []int{2, 3}, uploaded)
+	}
+	if _, err := store.Load(name); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Session of finished upload was not deleted. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+
+	driveContent, err := drive.Get(name)
+	if err != nil {
+		t.Fatalf("Unexpected error while trying to get file content: %v", err)
+	}
+	defer driveContent.Close()
+	content, _ := ioutil.ReadAll(driveContent)
+	if !bytes.Equal(b, content) {
+		t.Errorf("Fetched content not equal to expected.")
+	}
+}
+
+func TestUploadEmptyFile(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	s, err := drive.StartUpload("empty_file", "text/plain")
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+	name, err := drive.Upload(&UploadInput{Session: s, Body: strings.NewReader("")})
+	if err != nil {
+		t.Fatalf("Failed to upload empty file: %v", err)
+	}
+
+	driveContent, err := drive.Get(name)
+	if err != nil {
+		t.Fatalf("Unexpected error while trying to get file content: %v", err)
+	}
+	defer driveContent.Close()
+	content, _ := ioutil.ReadAll(driveContent)
+	if len(content) != 0 {
+		t.Errorf("Fetched content not empty. Got: %v", content)
+	}
+
+	if _, err := drive.Upload(&UploadInput{Session: &UploadSession{}, Body: strings.NewReader("")}); !errors.Is(err, deta.ErrBadUploadSession) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadUploadSession, err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	b := make([]byte, readChunkSize*2+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	var uploads []Progress
+	name, err := drive.Put(&PutInput{
+		Name:        "progress_file",
+		Body:        bytes.NewReader(b),
+		Concurrency: 3,
+		OnProgress: func(p Progress) {
+			uploads = append(uploads, p)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put file with error %v", err)
+	}
+	if len(uploads) != 3 {
+		t.Fatalf("Unexpected number of progress reports. Expected: %v Got: %v", 3, len(uploads))
+	}
+	last := uploads[len(uploads)-1]
+	if last.Bytes != int64(len(b)) || last.Total != int64(len(b)) {
+		t.Errorf("Unexpected upload progress. Expected: %v/%v Got: %v/%v", len(b), len(b), last.Bytes, last.Total)
+	}
+	parts := 0
+	for _, p := range uploads {
+		parts += p.Part
+	}
+	if parts != 6 {
+		t.Errorf("Unexpected parts in progress reports. Expected: %v Got: %v", []int{1, 2, 3}, uploads)
+	}
+
+	var bytesRead, total int64
+	rc, err := drive.GetWithProgress(name, func(p Progress) {
+		if p.Bytes < bytesRead {
+			t.Errorf("Progress went backwards. Got: %v after %v", p.Bytes, bytesRead)
+		}
+		bytesRead, total = p.Bytes, p.Total
+	})
+	if err != nil {
+		t.Fatalf("Failed to get file: %v", err)
+	}
+	defer rc.Close()
+	if _, err := io.Copy(ioutil.Discard, rc); err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if bytesRead != int64(len(b)) || total != int64(len(b)) {
+		t.Errorf("Unexpected download progress. Expected: %v/%v Got: %v/%v", len(b), len(b), bytesRead, total)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	content := "0123456789abcdefghij"
+	name, err := drive.Put(&PutInput{Name: "range.txt", Body: strings.NewReader(content)})
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	testCases := []struct {
+		offset   int64
+		length   int64
+		expected string
+	}{
+		{0, 5, "01234"},
+		{10, 3, "abc"},
+		{15, -1, "fghij"},
+		{18, 10, "ij"},
+		{5, 0, ""},
+	}
+	for _, tc := range testCases {
+		rc, err := drive.GetRange(name, tc.offset, tc.length)
+		if err != nil {
+			t.Fatalf("Failed to get range %d+%d: %v", tc.offset, tc.length, err)
+		}
+		got, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(got) != tc.expected {
+			t.Errorf("Range %d+%d not as expected. Expected: %q Got: %q", tc.offset, tc.length, tc.expected, got)
+		}
+	}
+
+	if _, err := drive.GetRange(name, 100, 5); !errors.Is(err, deta.ErrRangeNotSatisfiable) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrRangeNotSatisfiable, err)
+	}
+	if _, err := drive.GetRange(name, -1, 5); !errors.Is(err, deta.ErrBadOffset) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadOffset, err)
+	}
+
+	// empty ranges are checked like other ranges
+	if _, err := drive.GetRange("", 0, 0); !errors.Is(err, deta.ErrEmptyName) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrEmptyName, err)
+	}
+	if _, err := drive.GetRange("missing.txt", 0, 0); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+	if _, err := drive.GetRange(name, 100, 0); !errors.Is(err, deta.ErrRangeNotSatisfiable) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrRangeNotSatisfiable, err)
+	}
+}
+
+func TestGetRangeIgnored(t *testing.T) {
+	// a server ignoring the range header sends the whole file
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	d, _ := deta.New(deta.WithProjectKey("project_key"), deta.WithDriveRootEndpoint(srv.URL))
+	drive, _ := New(d, "drive")
+
+	rc, err := drive.GetRange("file", 3, 4)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	defer rc.Close()
+	got, _ := ioutil.ReadAll(rc)
+	if string(got) != "3456" {
+		t.Errorf("Range not as expected. Expected: %q Got: %q", "3456", got)
+	}
+
+	f, err := drive.Open("file")
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	if f.Size() != 10 {
+		t.Errorf("Size not as expected. Expected: %v Got: %v", 10, f.Size())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	content := "0123456789abcdefghij"
+	name, err := drive.Put(&PutInput{Name: "open.txt", Body: strings.NewReader(content)})
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	f, err := drive.Open(name)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	if f.Size() != int64(len(content)) {
+		t.Errorf("Size not as expected. Expected: %v Got: %v", len(content), f.Size())
+	}
+
+	// read at offsets
+	p := make([]byte, 4)
+	n, err := f.ReadAt(p, 8)
+	if err != nil || string(p[:n]) != "89ab" {
+		t.Errorf("ReadAt not as expected. Expected: %q Got: %q (error: %v)", "89ab", p[:n], err)
+	}
+	n, err = f.ReadAt(p, 18)
+	if err != io.EOF || string(p[:n]) != "ij" {
+		t.Errorf("ReadAt at the end not as expected. Expected: %q %v Got: %q %v", "ij", io.EOF, p[:n], err)
+	}
+
+	// seek and read
+	if _, err := f.Seek(-5, io.SeekEnd); err != nil {
+		t.Fatalf("Failed to seek: %v", err)
+	}
+	rest, err := ioutil.ReadAll(f)
+	if err != nil || string(rest) != "fghij" {
+		t.Errorf("Read after seek not as expected. Expected: %q Got: %q (error: %v)", "fghij", rest, err)
+	}
+	if _, err := f.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek: %v", err)
+	}
+	n, err = io.ReadFull(f, p)
+	if err != nil || string(p[:n]) != "2345" {
+		t.Errorf("Read after seek not as expected. Expected: %q Got: %q (error: %v)", "2345", p[:n], err)
+	}
+
+	// empty files
+	s, err := drive.StartUpload("empty.txt", "text/plain")
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+	if _, err := drive.Upload(&UploadInput{Session: s, Body: strings.NewReader("")}); err != nil {
+		t.Fatalf("Failed to upload empty file: %v", err)
+	}
+	ef, err := drive.Open("empty.txt")
+	if err != nil {
+		t.Fatalf("Failed to open empty file: %v", err)
+	}
+	if ef.Size() != 0 {
+		t.Errorf("Size not as expected. Expected: %v Got: %v", 0, ef.Size())
+	}
+	if _, err := ef.Read(p); err != io.EOF {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", io.EOF, err)
+	}
+
+	if _, err := drive.Open("missing.txt"); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	content := "some text content"
+	name, err := drive.Put(&PutInput{
+		Name:        "stat.txt",
+		Body:        strings.NewReader(content),
+		ContentType: "text/plain",
+	})
+	if err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	fi, err := drive.Stat(name)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if fi.Name != name || fi.Size != int64(len(content)) || fi.ContentType != "text/plain" {
+		t.Errorf("File info not as expected. Expected: %v %v %v Got: %v %v %v",
+			name, len(content), "text/plain", fi.Name, fi.Size, fi.ContentType)
+	}
+	if testServer != nil && (fi.ETag == "" || fi.LastModified.IsZero()) {
+		t.Errorf("File info is missing the etag or last modified time. Got: %+v", fi)
+	}
+
+	exists, err := drive.Exists(name)
+	if err != nil || !exists {
+		t.Errorf("Unexpected existence of file. Expected: %v Got: %v (error: %v)", true, exists, err)
+	}
+	exists, err = drive.Exists("missing.txt")
+	if err != nil || exists {
+		t.Errorf("Unexpected existence of missing file. Expected: %v Got: %v (error: %v)", false, exists, err)
+	}
+	if _, err := drive.Stat("missing.txt"); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	b := make([]byte, readChunkSize*2+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	w, err := drive.Create("created_file", "application/octet-stream")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	// write in uneven pieces crossing the part boundaries
+	for rest := b; len(rest) > 0; {
+		n := 3*1024*1024 + 7
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatalf("Failed to write to file: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close file: %v", err)
+	}
+	if _, err := w.Write([]byte("more")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", os.ErrClosed, err)
+	}
+
+	driveContent, err := drive.Get("created_file")
+	if err != nil {
+		t.Fatalf("Unexpected error while trying to get file content: %v", err)
+	}
+	defer driveContent.Close()
+	content, _ := ioutil.ReadAll(driveContent)
+	if !bytes.Equal(b, content) {
+		t.Errorf("Fetched content not equal to expected.")
+	}
+
+	// empty files
+	w, err = drive.Create("created_empty_file", "text/plain")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close empty file: %v", err)
+	}
+	if fi, err := drive.Stat("created_empty_file"); err != nil || fi.Size != 0 {
+		t.Errorf("Empty file not as expected. Got: %+v (error: %v)", fi, err)
+	}
+
+	// aborted files
+	w, err = drive.Create("aborted_file", "text/plain")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	w.Write([]byte("content"))
+	writeErr := errors.New("failed to produce content")
+	if err := w.CloseWithError(writeErr); err != nil {
+		t.Fatalf("Failed to abort file: %v", err)
+	}
+	if _, err := w.Write([]byte("more")); err != writeErr {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", writeErr, err)
+	}
+	if exists, _ := drive.Exists("aborted_file"); exists {
+		t.Errorf("Aborted file exists")
+	}
+}
+
+func TestCreateCtxCancelAbortsUpload(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/uploads"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"upload_id": "upload_id"}`))
+		case r.Method == "DELETE" && strings.HasSuffix(r.URL.Path, "/uploads/upload_id"):
+			aborted <- struct{}{}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	d, _ := deta.New(deta.WithProjectKey("project_key"), deta.WithDriveRootEndpoint(srv.URL))
+	drive, _ := New(d, "drive")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := drive.CreateCtx(ctx, "cancelled.txt", "text/plain")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	w.Write([]byte("content"))
+
+	// the writer is dropped without being written to or closed again
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Cancelled upload was not aborted")
+	}
+	if _, err := w.Write([]byte("more")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.Canceled, err)
+	}
+}
+
+// returns at most max bytes per read
+type shortReader struct {
+	r   io.Reader
+	max int
+}
+
+func (sr *shortReader) Read(p []byte) (int, error) {
+	if len(p) > sr.max {
+		p = p[:sr.max]
+	}
+	return sr.r.Read(p)
+}
+
+func TestPutShortReads(t *testing.T) {
+	if testServer == nil {
+		t.Skip("counting parts requires the fake server")
+	}
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	b := make([]byte, readChunkSize*2+1000)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed to generate random large file with error %v", err)
+	}
+
+	var parts []int
+	testServer.FailUploadPart = func(name string, part int) bool {
+		parts = append(parts, part)
+		return false
+	}
+	defer func() { testServer.FailUploadPart = nil }()
+
+	testCases := []struct {
+		name     string
+		body     io.Reader
+		content  []byte
+		numParts int
+	}{
+		{"short_reads", &shortReader{r: bytes.NewReader(b), max: 64 * 1024}, b, 3},
+		{"data_with_eof", iotest.DataErrReader(bytes.NewReader(b)), b, 3},
+		{"one_part_with_eof", iotest.DataErrReader(strings.NewReader("content")), []byte("content"), 1},
+		{"exact_part", bytes.NewReader(b[:readChunkSize]), b[:readChunkSize], 1},
+		{"empty", strings.NewReader(""), []byte{}, 1},
+	}
+
+	for _, tc := range testCases {
+		parts = nil
+		name, err := drive.Put(&PutInput{Name: tc.name, Body: tc.body})
+		if err != nil {
+			t.Fatalf("Failed to put file %s: %v", tc.name, err)
+		}
+		if len(parts) != tc.numParts {
+			t.Errorf("Unexpected number of parts for %s. Expected: %v Got: %v", tc.name, tc.numParts, len(parts))
+		}
+
+		driveContent, err := drive.Get(name)
+		if err != nil {
+			t.Fatalf("Unexpected error while trying to get file content: %v", err)
+		}
+		content, _ := ioutil.ReadAll(driveContent)
+		driveContent.Close()
+		if !bytes.Equal(tc.content, content) {
+			t.Errorf("Fetched content of %s not equal to expected.", tc.name)
+		}
+	}
+}
+
+func TestPutAbortError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/uploads"):
+			w.Write([]byte(`{"upload_id": "upload_id"}`))
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/parts"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": ["bad part"]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"errors": ["failed to abort"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	d, _ := deta.New(deta.WithProjectKey("project_key"), deta.WithDriveRootEndpoint(srv.URL))
+	drive, _ := New(d, "drive")
+
+	_, err := drive.Put(&PutInput{Name: "file", Body: strings.NewReader("content")})
+	var abortErr *AbortError
+	if !errors.As(err, &abortErr) {
+		t.Fatalf("Unexpected error type. Expected: %T Got: %T", abortErr, err)
+	}
+	if !errors.Is(err, deta.ErrBadRequest) || !errors.Is(abortErr.AbortErr, deta.ErrInternalServerError) {
+		t.Errorf("Unexpected errors. Expected: %v and %v Got: %v", deta.ErrBadRequest, deta.ErrInternalServerError, err)
+	}
+}
+
+func TestStatUnknownSize(t *testing.T) {
+	for _, headSize := range []string{"", "7"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			if r.Method == "HEAD" {
+				if headSize != "" {
+					w.Header().Set("Content-Length", headSize)
+				}
+				return
+			}
+			// ignore the range and stream the whole file without its length
+			w.Write([]byte("con"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("tent"))
+		}))
+
+		d, _ := deta.New(deta.WithProjectKey("project_key"), deta.WithDriveRootEndpoint(srv.URL))
+		drive, _ := New(d, "drive")
+
+		expectedSize := int64(-1)
+		if headSize != "" {
+			expectedSize = 7
+		}
+		fi, err := drive.Stat("file")
+		if err != nil {
+			t.Fatalf("Unexpected error while trying to stat file: %v", err)
+		}
+		if fi.Size != expectedSize {
+			t.Errorf("Unexpected size. Expected: %d Got: %d", expectedSize, fi.Size)
+		}
+		exists, err := drive.Exists("file")
+		if err != nil || !exists {
+			t.Errorf("Unexpected exists. Expected: true Got: %v, %v", exists, err)
+		}
+		srv.Close()
+	}
+}
+
+// writes the files to the directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+// returns the operations of the sync actions by path
+func syncOps(actions []*SyncAction) map[string]SyncOp {
+	ops := make(map[string]SyncOp)
+	for _, a := range actions {
+		ops[a.Path] = a.Op
+	}
+	return ops
+}
+
+func TestSyncUpload(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	dir, err := ioutil.TempDir("", "deta-sync")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"index.html":     "<html></html>",
+		"css/style.css":  "body {}",
+		"js/app.js":      "app()",
+		"js/app.js.map":  "{}",
+		"img/logo.svg":   "<svg></svg>",
+		"img/unchanged":  "same",
+		"img/same_size":  "aaaa",
+		"drafts/new.txt": "draft",
+	})
+	for name, content := range map[string]string{
+		"assets/img/unchanged": "same",
+		"assets/img/same_size": "bbbb",
+		"assets/css/style.css": "body { color: red }",
+		"assets/old.txt":       "old",
+		"assets/old.map":       "{}",
+		"other/file.txt":       "other",
+	} {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(content)}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	i := &SyncInput{
+		LocalDir: dir,
+		Prefix:   "assets",
+		Delete:   true,
+		Exclude:  []string{"*.map", "drafts/*"},
+		DryRun:   true,
+	}
+	expected := map[string]SyncOp{
+		"index.html":    SyncCreate,
+		"css/style.css": SyncUpdate,
+		"js/app.js":     SyncCreate,
+		"img/logo.svg":  SyncCreate,
+		"old.txt":       SyncDelete,
+		// files of the same size are compared by hash
+		"img/same_size": SyncUpdate,
+	}
+
+	out, err := drive.Sync(i)
+	if err != nil {
+		t.Fatalf("Failed to dry run sync: %v", err)
+	}
+	if ops := syncOps(out.Actions); !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Sync operations not as expected. Expected: %v Got: %v", expected, ops)
+	}
+	if exists, _ := drive.Exists("assets/index.html"); exists {
+		t.Errorf("Dry run uploaded a file")
+	}
+
+	i.DryRun = false
+	out, err = drive.Sync(i)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if ops := syncOps(out.Actions); !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Sync operations not as expected. Expected: %v Got: %v", expected, ops)
+	}
+
+	lr, err := drive.List(1000, "", "")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	names := []string{
+		"assets/css/style.css", "assets/img/logo.svg", "assets/img/same_size", "assets/img/unchanged",
+		"assets/index.html", "assets/js/app.js", "assets/old.map", "other/file.txt",
+	}
+	if !reflect.DeepEqual(lr.Names, names) {
+		t.Errorf("Synced files not as expected. Expected: %v Got: %v", names, lr.Names)
+	}
+	rc, err := drive.Get("assets/css/style.css")
+	if err != nil {
+		t.Fatalf("Failed to get file: %v", err)
+	}
+	content, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if string(content) != "body {}" {
+		t.Errorf("Synced content not as expected. Expected: %q Got: %q", "body {}", content)
+	}
+
+	// a second sync has nothing to do
+	out, err = drive.Sync(i)
+	if err != nil || len(out.Actions) != 0 {
+		t.Errorf("Unexpected operations of a second sync. Expected: %v Got: %v (error: %v)", 0, len(out.Actions), err)
+	}
+
+	if _, err := drive.Sync(&SyncInput{LocalDir: filepath.Join(dir, "missing")}); !os.IsNotExist(err) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", os.ErrNotExist, err)
+	}
+	if _, err := drive.Sync(&SyncInput{LocalDir: dir, Include: []string{"["}}); !errors.Is(err, deta.ErrBadSyncInput) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadSyncInput, err)
+	}
+}
+
+func TestSyncDownload(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	dir, err := ioutil.TempDir("", "deta-sync")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"a.txt":       "old",
+		"orphan.txt":  "orphan",
+		"keep/b.txt":  "b",
+		"keep/c.json": "{}",
+	})
+	for name, content := range map[string]string{
+		"backup/a.txt":      "new",
+		"backup/keep/b.txt": "b",
+		"backup/d/e.txt":    "e",
+	} {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(content)}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	out, err := drive.Sync(&SyncInput{
+		LocalDir:    dir,
+		Prefix:      "backup/",
+		Direction:   SyncDownload,
+		Delete:      true,
+		Include:     []string{"*.txt"},
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	expected := map[string]SyncOp{
+		"a.txt":      SyncUpdate,
+		"d/e.txt":    SyncCreate,
+		"orphan.txt": SyncDelete,
+	}
+	if ops := syncOps(out.Actions); !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Sync operations not as expected. Expected: %v Got: %v", expected, ops)
+	}
+	if out.Unchanged != 1 {
+		t.Errorf("Unchanged files not as expected. Expected: %v Got: %v", 1, out.Unchanged)
+	}
+
+	for name, content := range map[string]string{"a.txt": "new", "d/e.txt": "e", "keep/c.json": "{}"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != content {
+			t.Errorf("Synced file %s not as expected. Expected: %q Got: %q (error: %v)", name, content, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "orphan.txt")); !os.IsNotExist(err) {
+		t.Errorf("Orphaned file was not deleted")
+	}
+}
+
+// an http.RoundTripper cancelling a context on the first download of a whole file and counting
+// the requests after it
+type cancelDownloadTransport struct {
+	cancel    func()
+	cancelled int32
+	after     int32
+}
+
+func (t *cancelDownloadTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if atomic.LoadInt32(&t.cancelled) == 1 {
+		atomic.AddInt32(&t.after, 1)
+	} else if r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/files/download") && r.Header.Get("Range") == "" {
+		atomic.StoreInt32(&t.cancelled, 1)
+		t.cancel()
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestSyncCancel(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Counting requests is only tested with the fake server")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	transport := &cancelDownloadTransport{cancel: cancel}
+	d, _ := testServer.Deta(deta.WithTransport(transport))
+	drive, _ := New(d, "test_drive")
+	defer TearDownDrive(drive, t)
+
+	dir, err := ioutil.TempDir("", "deta-sync")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	names := []string{"a.txt", "b.txt", "c.txt", "d.txt"}
+	for _, name := range names {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(name)}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	out, err := drive.SyncCtx(ctx, &SyncInput{LocalDir: dir, Direction: SyncDownload, Concurrency: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.Canceled, err)
+	}
+	if after := atomic.LoadInt32(&transport.after); after != 0 {
+		t.Errorf("Unexpected requests after cancel. Expected: %d Got: %d", 0, after)
+	}
+	if out == nil || len(out.Actions) != len(names) {
+		t.Fatalf("Unexpected sync output: %v", out)
+	}
+	for _, a := range out.Actions {
+		if !errors.Is(a.Err, context.Canceled) {
+			t.Errorf("Unexpected error of %s. Expected: %v Got: %v", a.Path, context.Canceled, a.Err)
+		}
+	}
+}
+
+// returns the content and content type of a file
+func getFile(t *testing.T, d API, name string) (string, string) {
+	t.Helper()
+	fi, err := d.Stat(name)
+	if err != nil {
+		t.Fatalf("Failed to stat file %s: %v", name, err)
+	}
+	rc, err := d.Get(name)
+	if err != nil {
+		t.Fatalf("Failed to get file %s: %v", name, err)
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Failed to read file %s: %v", name, err)
+	}
+	return string(content), fi.ContentType
+}
+
+func TestCopy(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	// a file of multiple parts
+	large := make([]byte, uploadChunkSize+1024)
+	rand.Read(large)
+	for name, content := range map[string]string{"small.txt": "some text", "large.bin": string(large)} {
+		if _, err := drive.Put(&PutInput{
+			Name:        name,
+			Body:        strings.NewReader(content),
+			ContentType: "text/plain",
+		}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+
+		copied, err := drive.Copy(name, "copies/"+name)
+		if err != nil {
+			t.Fatalf("Failed to copy file: %v", err)
+		}
+		if copied != "copies/"+name {
+			t.Errorf("Unexpected name of copy. Expected: %s Got: %s", "copies/"+name, copied)
+		}
+		got, contentType := getFile(t, drive, copied)
+		if got != content || contentType != "text/plain" {
+			t.Errorf("Copy of %s not equal to the file. Expected content type: %s Got: %s", name, "text/plain", contentType)
+		}
+	}
+
+	if _, err := drive.Copy("missing.txt", "copy.txt"); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+	if _, err := drive.Copy("small.txt", ""); !errors.Is(err, deta.ErrEmptyName) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrEmptyName, err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	if _, err := drive.Put(&PutInput{
+		Name:        "a.txt",
+		Body:        strings.NewReader("content"),
+		ContentType: "text/plain",
+	}); err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	// moving a file onto itself keeps it
+	if name, err := drive.Move("a.txt", "a.txt"); err != nil || name != "a.txt" {
+		t.Fatalf("Unexpected move onto itself. Expected: %s Got: %s, %v", "a.txt", name, err)
+	}
+	name, err := drive.Move("a.txt", "b.txt")
+	if err != nil || name != "b.txt" {
+		t.Fatalf("Unexpected move result. Expected: %s Got: %s, %v", "b.txt", name, err)
+	}
+	if exists, _ := drive.Exists("a.txt"); exists {
+		t.Errorf("Moved file still exists")
+	}
+	if content, contentType := getFile(t, drive, "b.txt"); content != "content" || contentType != "text/plain" {
+		t.Errorf("Unexpected moved file. Expected: %s %s Got: %s %s", "content", "text/plain", content, contentType)
+	}
+	if _, err := drive.Move("a.txt", "c.txt"); !errors.Is(err, deta.ErrNotFound) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrNotFound, err)
+	}
+}
+
+func TestCopyPrefix(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Copying across drives is only tested with the fake server")
+	}
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+	d, _ := testServer.Deta()
+	other, _ := New(d, "test_drive_other")
+	defer TearDownDrive(other, t)
+
+	for _, name := range []string{"logs/a.txt", "logs/2021/b.txt", "logs/2021/c.txt", "other.txt"} {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(name), ContentType: "text/plain"}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	// copies under the source prefix are not copied again
+	out, err := drive.CopyPrefix(&CopyPrefixInput{Src: "logs/", Dst: "logs/old/", Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Failed to copy prefix: %v", err)
+	}
+	expected := []string{"logs/old/2021/b.txt", "logs/old/2021/c.txt", "logs/old/a.txt"}
+	if !reflect.DeepEqual(out.Copied, expected) || len(out.Failed) != 0 {
+		t.Errorf("Unexpected copied files. Expected: %v Got: %v (failed: %v)", expected, out.Copied, out.Failed)
+	}
+	if content, _ := getFile(t, drive, "logs/old/2021/b.txt"); content != "logs/2021/b.txt" {
+		t.Errorf("Unexpected content of copy. Expected: %s Got: %s", "logs/2021/b.txt", content)
+	}
+
+	out, err = drive.CopyPrefix(&CopyPrefixInput{Src: "logs/2021/", Dst: "2021/", To: other})
+	if err != nil {
+		t.Fatalf("Failed to copy prefix to other drive: %v", err)
+	}
+	lr, err := other.List(1000, "", "")
+	if err != nil {
+		t.Fatalf("Failed to list other drive: %v", err)
+	}
+	expected = []string{"2021/b.txt", "2021/c.txt"}
+	if !reflect.DeepEqual(lr.Names, expected) || !reflect.DeepEqual(out.Copied, expected) {
+		t.Errorf("Unexpected files in other drive. Expected: %v Got: %v (copied: %v)", expected, lr.Names, out.Copied)
+	}
+	if content, contentType := getFile(t, other, "2021/c.txt"); content != "logs/2021/c.txt" || contentType != "text/plain" {
+		t.Errorf("Unexpected copy in other drive. Expected: %s %s Got: %s %s", "logs/2021/c.txt", "text/plain", content, contentType)
+	}
+
+	if _, err := drive.CopyPrefix(&CopyPrefixInput{Src: "logs/", Dst: "logs/"}); !errors.Is(err, deta.ErrBadCopyInput) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrBadCopyInput, err)
+	}
+
+	// a failed upload fails the copy of its file only
+	testServer.FailUploadPart = func(name string, part int) bool {
+		return name == "failed/other.txt"
+	}
+	defer func() { testServer.FailUploadPart = nil }()
+	out, err = drive.CopyPrefix(&CopyPrefixInput{Src: "", Dst: "failed/", To: other})
+	if !errors.Is(err, deta.ErrInternalServerError) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrInternalServerError, err)
+	}
+	if len(out.Copied) != 6 || len(out.Failed) != 1 || out.Failed["other.txt"] == nil {
+		t.Errorf("Unexpected copy output. Copied: %v Failed: %v", out.Copied, out.Failed)
+	}
+}
+
+// a destination drive cancelling the context on the first put
+type cancelPutDrive struct {
+	API
+	cancel func()
+}
+
+func (d *cancelPutDrive) PutCtx(ctx context.Context, i *PutInput) (string, error) {
+	d.cancel()
+	return "", ctx.Err()
+}
+
+// an http.RoundTripper counting the downloads of files
+type countingTransport struct {
+	downloads int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/files/download") {
+		atomic.AddInt32(&t.downloads, 1)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestCopyPrefixCancel(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Counting requests is only tested with the fake server")
+	}
+	transport := &countingTransport{}
+	d, _ := testServer.Deta(deta.WithTransport(transport))
+	drive, _ := New(d, "test_drive")
+	defer TearDownDrive(drive, t)
+
+	names := []string{"logs/a.txt", "logs/b.txt", "logs/c.txt", "logs/d.txt"}
+	for _, name := range names {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(name)}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, err := drive.CopyPrefixCtx(ctx, &CopyPrefixInput{Src: "logs/", Dst: "old/", To: &cancelPutDrive{cancel: cancel}, Concurrency: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", context.Canceled, err)
+	}
+	if downloads := atomic.LoadInt32(&transport.downloads); downloads != 1 {
+		t.Errorf("Unexpected copies started after cancel. Expected: %d Got: %d", 1, downloads)
+	}
+	if len(out.Copied) != 0 || len(out.Failed) != len(names) {
+		t.Errorf("Unexpected copy output. Copied: %v Failed: %v", out.Copied, out.Failed)
+	}
+}
+
+func TestDeleteManyBatches(t *testing.T) {
+	if testServer == nil {
+		t.Skip("Deleting thousands of files is only tested with the fake server")
+	}
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	var names []string
+	for n := 0; n < 2*maxDeleteNames+10; n++ {
+		name, err := drive.Put(&PutInput{Name: fmt.Sprintf("logs/%04d.txt", n), Body: strings.NewReader("log")})
+		if err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+		names = append(names, name)
+	}
+	if _, err := drive.Put(&PutInput{Name: "logs.txt", Body: strings.NewReader("kept")}); err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+
+	out, err := drive.DeleteMany(names[:maxDeleteNames+10])
+	if err != nil {
+		t.Fatalf("Failed to delete files: %v", err)
+	}
+	if !reflect.DeepEqual(out.Deleted, names[:maxDeleteNames+10]) || len(out.Failed) != 0 {
+		t.Errorf("Unexpected deleted files. Expected: %d files Got: %d files (failed: %v)", maxDeleteNames+10, len(out.Deleted), out.Failed)
+	}
+
+	// the remaining files span two list pages
+	out, err = drive.DeletePrefix("logs/")
+	if err != nil {
+		t.Fatalf("Failed to delete files with prefix: %v", err)
+	}
+	if !reflect.DeepEqual(out.Deleted, names[maxDeleteNames+10:]) || len(out.Failed) != 0 {
+		t.Errorf("Unexpected deleted files. Expected: %d files Got: %d files (failed: %v)", maxDeleteNames, len(out.Deleted), out.Failed)
+	}
+	lr, err := drive.List(1000, "", "")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if !reflect.DeepEqual(lr.Names, []string{"logs.txt"}) {
+		t.Errorf("Unexpected remaining files. Expected: %v Got: %v", []string{"logs.txt"}, lr.Names)
+	}
+
+	if _, err := drive.DeletePrefix(""); !errors.Is(err, deta.ErrEmptyName) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrEmptyName, err)
+	}
+}
+
+func TestSyncMultipartSameSize(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	dir, err := ioutil.TempDir("", "deta-sync")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// multipart files have no md5 hash as their etag
+	content := make([]byte, uploadChunkSize+10)
+	rand.Read(content)
+	if _, err := drive.Put(&PutInput{Name: "large.bin", Body: bytes.NewReader(content)}); err != nil {
+		t.Fatalf("Failed to put file: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.bin"), content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	i := &SyncInput{LocalDir: dir, DryRun: true}
+	out, err := drive.Sync(i)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(out.Actions) != 0 || out.Unchanged != 1 {
+		t.Errorf("Unexpected sync of unchanged file. Actions: %v Unchanged: %d", out.Actions, out.Unchanged)
+	}
+
+	// a change keeping the size
+	content[len(content)-1]++
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.bin"), content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	out, err = drive.Sync(i)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	expected := map[string]SyncOp{"large.bin": SyncUpdate}
+	if ops := syncOps(out.Actions); !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Sync operations not as expected. Expected: %v Got: %v", expected, ops)
+	}
+}
+
+func TestSyncDownloadUnsafeName(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	parent, err := ioutil.TempDir("", "deta-sync")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "local")
+
+	for name, content := range map[string]string{
+		"site/ok.txt":         "ok",
+		"site/../escaped.txt": "escaped",
+	} {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(content)}); err != nil {
+			t.Fatalf("Failed to put file: %v", err)
+		}
+	}
+
+	out, err := drive.Sync(&SyncInput{LocalDir: dir, Prefix: "site", Direction: SyncDownload})
+	if !errors.Is(err, deta.ErrUnsafeName) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", deta.ErrUnsafeName, err)
+	}
+	if out == nil || len(out.Actions) != 2 {
+		t.Fatalf("Unexpected sync output: %v", out)
+	}
+	for _, a := range out.Actions {
+		if failed := a.Path == "../escaped.txt"; failed != (a.Err != nil) {
+			t.Errorf("Unexpected result of %s: %v", a.Path, a.Err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("File was written outside of the local directory")
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(dir, "ok.txt")); err != nil || string(got) != "ok" {
+		t.Errorf("Synced file not as expected. Expected: %q Got: %q (error: %v)", "ok", got, err)
+	}
+}
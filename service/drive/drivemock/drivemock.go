@@ -0,0 +1,395 @@
+// Package drivemock provides a mock of the Deta Drive service client for tests.
+//
+// Set the function fields of a Drive for the methods used by the code under test.
+// A method without a Ctx suffix falls back to the function of its Ctx variant
+// when its own function is not set.
+//
+//	mock := &drivemock.Drive{
+//		GetCtxFunc: func(ctx context.Context, name string) (io.ReadCloser, error) {
+//			return ioutil.NopCloser(strings.NewReader("content")), nil
+//		},
+//	}
+//	var api drive.API = mock
+//
+// Calling a method without a function set panics. NewFile and NewWriter return in-memory
+// handles for the functions of Open and Create.
+package drivemock
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/deta/deta-go/service/drive"
+)
+
+// Drive is a mock of drive.API
+type Drive struct {
+	GetFunc                func(name string) (io.ReadCloser, error)
+	GetCtxFunc             func(ctx context.Context, name string) (io.ReadCloser, error)
+	GetWithProgressFunc    func(name string, fn drive.ProgressFunc) (io.ReadCloser, error)
+	GetWithProgressCtxFunc func(ctx context.Context, name string, fn drive.ProgressFunc) (io.ReadCloser, error)
+	GetRangeFunc           func(name string, offset, length int64) (io.ReadCloser, error)
+	GetRangeCtxFunc        func(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error)
+	OpenFunc               func(name string) (drive.FileReader, error)
+	OpenCtxFunc            func(ctx context.Context, name string) (drive.FileReader, error)
+	StatFunc               func(name string) (*drive.FileInfo, error)
+	StatCtxFunc            func(ctx context.Context, name string) (*drive.FileInfo, error)
+	ExistsFunc             func(name string) (bool, error)
+	ExistsCtxFunc          func(ctx context.Context, name string) (bool, error)
+	PutFunc                func(i *drive.PutInput) (string, error)
+	PutCtxFunc             func(ctx context.Context, i *drive.PutInput) (string, error)
+	StartUploadFunc        func(name, contentType string) (*drive.UploadSession, error)
+	StartUploadCtxFunc     func(ctx context.Context, name, contentType string) (*drive.UploadSession, error)
+	UploadFunc             func(i *drive.UploadInput) (string, error)
+	UploadCtxFunc          func(ctx context.Context, i *drive.UploadInput) (string, error)
+	AbortUploadFunc        func(s *drive.UploadSession) error
+	AbortUploadCtxFunc     func(ctx context.Context, s *drive.UploadSession) error
+	CreateFunc             func(name, contentType string) (drive.FileWriter, error)
+	CreateCtxFunc          func(ctx context.Context, name, contentType string) (drive.FileWriter, error)
+	ListFunc               func(limit int, prefix, last string) (*drive.ListOutput, error)
+	ListCtxFunc            func(ctx context.Context, limit int, prefix, last string) (*drive.ListOutput, error)
+	DeleteManyFunc         func(names []string) (*drive.DeleteManyOutput, error)
+	DeleteManyCtxFunc      func(ctx context.Context, names []string) (*drive.DeleteManyOutput, error)
+	DeleteFunc             func(name string) (string, error)
+	DeleteCtxFunc          func(ctx context.Context, name string) (string, error)
+	DeletePrefixFunc       func(prefix string) (*drive.DeleteManyOutput, error)
+	DeletePrefixCtxFunc    func(ctx context.Context, prefix string) (*drive.DeleteManyOutput, error)
+	SyncFunc               func(i *drive.SyncInput) (*drive.SyncOutput, error)
+	SyncCtxFunc            func(ctx context.Context, i *drive.SyncInput) (*drive.SyncOutput, error)
+	CopyFunc               func(src, dst string) (string, error)
+	CopyCtxFunc            func(ctx context.Context, src, dst string) (string, error)
+	CopyToFunc             func(to drive.API, src, dst string) (string, error)
+	CopyToCtxFunc          func(ctx context.Context, to drive.API, src, dst string) (string, error)
+	MoveFunc               func(src, dst string) (string, error)
+	MoveCtxFunc            func(ctx context.Context, src, dst string) (string, error)
+	CopyPrefixFunc         func(i *drive.CopyPrefixInput) (*drive.CopyPrefixOutput, error)
+	CopyPrefixCtxFunc      func(ctx context.Context, i *drive.CopyPrefixInput) (*drive.CopyPrefixOutput, error)
+}
+
+var _ drive.API = (*Drive)(nil)
+
+// panics for a call to a method without a function set
+func unexpected(method string) {
+	panic(fmt.Sprintf("drivemock: unexpected call to Drive.%s, %sFunc is not set", method, method))
+}
+
+// Get calls GetFunc, or GetCtxFunc with a background context
+func (m *Drive) Get(name string) (io.ReadCloser, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(name)
+	}
+	return m.GetCtx(context.Background(), name)
+}
+
+// GetCtx calls GetCtxFunc
+func (m *Drive) GetCtx(ctx context.Context, name string) (io.ReadCloser, error) {
+	if m.GetCtxFunc == nil {
+		unexpected("GetCtx")
+	}
+	return m.GetCtxFunc(ctx, name)
+}
+
+// GetWithProgress calls GetWithProgressFunc, or GetWithProgressCtxFunc with a background context
+func (m *Drive) GetWithProgress(name string, fn drive.ProgressFunc) (io.ReadCloser, error) {
+	if m.GetWithProgressFunc != nil {
+		return m.GetWithProgressFunc(name, fn)
+	}
+	return m.GetWithProgressCtx(context.Background(), name, fn)
+}
+
+// GetWithProgressCtx calls GetWithProgressCtxFunc
+func (m *Drive) GetWithProgressCtx(ctx context.Context, name string, fn drive.ProgressFunc) (io.ReadCloser, error) {
+	if m.GetWithProgressCtxFunc == nil {
+		unexpected("GetWithProgressCtx")
+	}
+	return m.GetWithProgressCtxFunc(ctx, name, fn)
+}
+
+// GetRange calls GetRangeFunc, or GetRangeCtxFunc with a background context
+func (m *Drive) GetRange(name string, offset, length int64) (io.ReadCloser, error) {
+	if m.GetRangeFunc != nil {
+		return m.GetRangeFunc(name, offset, length)
+	}
+	return m.GetRangeCtx(context.Background(), name, offset, length)
+}
+
+// GetRangeCtx calls GetRangeCtxFunc
+func (m *Drive) GetRangeCtx(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	if m.GetRangeCtxFunc == nil {
+		unexpected("GetRangeCtx")
+	}
+	return m.GetRangeCtxFunc(ctx, name, offset, length)
+}
+
+// Open calls OpenFunc, or OpenCtxFunc with a background context
+func (m *Drive) Open(name string) (drive.FileReader, error) {
+	if m.OpenFunc != nil {
+		return m.OpenFunc(name)
+	}
+	return m.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx calls OpenCtxFunc
+func (m *Drive) OpenCtx(ctx context.Context, name string) (drive.FileReader, error) {
+	if m.OpenCtxFunc == nil {
+		unexpected("OpenCtx")
+	}
+	return m.OpenCtxFunc(ctx, name)
+}
+
+// Stat calls StatFunc, or StatCtxFunc with a background context
+func (m *Drive) Stat(name string) (*drive.FileInfo, error) {
+	if m.StatFunc != nil {
+		return m.StatFunc(name)
+	}
+	return m.StatCtx(context.Background(), name)
+}
+
+// StatCtx calls StatCtxFunc
+func (m *Drive) StatCtx(ctx context.Context, name string) (*drive.FileInfo, error) {
+	if m.StatCtxFunc == nil {
+		unexpected("StatCtx")
+	}
+	return m.StatCtxFunc(ctx, name)
+}
+
+// Exists calls ExistsFunc, or ExistsCtxFunc with a background context
+func (m *Drive) Exists(name string) (bool, error) {
+	if m.ExistsFunc != nil {
+		return m.ExistsFunc(name)
+	}
+	return m.ExistsCtx(context.Background(), name)
+}
+
+// ExistsCtx calls ExistsCtxFunc
+func (m *Drive) ExistsCtx(ctx context.Context, name string) (bool, error) {
+	if m.ExistsCtxFunc == nil {
+		unexpected("ExistsCtx")
+	}
+	return m.ExistsCtxFunc(ctx, name)
+}
+
+// Put calls PutFunc, or PutCtxFunc with a background context
+func (m *Drive) Put(i *drive.PutInput) (string, error) {
+	if m.PutFunc != nil {
+		return m.PutFunc(i)
+	}
+	return m.PutCtx(context.Background(), i)
+}
+
+// PutCtx calls PutCtxFunc
+func (m *Drive) PutCtx(ctx context.Context, i *drive.PutInput) (string, error) {
+	if m.PutCtxFunc == nil {
+		unexpected("PutCtx")
+	}
+	return m.PutCtxFunc(ctx, i)
+}
+
+// StartUpload calls StartUploadFunc, or StartUploadCtxFunc with a background context
+func (m *Drive) StartUpload(name, contentType string) (*drive.UploadSession, error) {
+	if m.StartUploadFunc != nil {
+		return m.StartUploadFunc(name, contentType)
+	}
+	return m.StartUploadCtx(context.Background(), name, contentType)
+}
+
+// StartUploadCtx calls StartUploadCtxFunc
+func (m *Drive) StartUploadCtx(ctx context.Context, name, contentType string) (*drive.UploadSession, error) {
+	if m.StartUploadCtxFunc == nil {
+		unexpected("StartUploadCtx")
+	}
+	return m.StartUploadCtxFunc(ctx, name, contentType)
+}
+
+// Upload calls UploadFunc, or UploadCtxFunc with a background context
+func (m *Drive) Upload(i *drive.UploadInput) (string, error) {
+	if m.UploadFunc != nil {
+		return m.UploadFunc(i)
+	}
+	return m.UploadCtx(context.Background(), i)
+}
+
+// UploadCtx calls UploadCtxFunc
+func (m *Drive) UploadCtx(ctx context.Context, i *drive.UploadInput) (string, error) {
+	if m.UploadCtxFunc == nil {
+		unexpected("UploadCtx")
+	}
+	return m.UploadCtxFunc(ctx, i)
+}
+
+// AbortUpload calls AbortUploadFunc, or AbortUploadCtxFunc with a background context
+func (m *Drive) AbortUpload(s *drive.UploadSession) error {
+	if m.AbortUploadFunc != nil {
+		return m.AbortUploadFunc(s)
+	}
+	return m.AbortUploadCtx(context.Background(), s)
+}
+
+// AbortUploadCtx calls AbortUploadCtxFunc
+func (m *Drive) AbortUploadCtx(ctx context.Context, s *drive.UploadSession) error {
+	if m.AbortUploadCtxFunc == nil {
+		unexpected("AbortUploadCtx")
+	}
+	return m.AbortUploadCtxFunc(ctx, s)
+}
+
+// Create calls CreateFunc, or CreateCtxFunc with a background context
+func (m *Drive) Create(name, contentType string) (drive.FileWriter, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(name, contentType)
+	}
+	return m.CreateCtx(context.Background(), name, contentType)
+}
+
+// CreateCtx calls CreateCtxFunc
+func (m *Drive) CreateCtx(ctx context.Context, name, contentType string) (drive.FileWriter, error) {
+	if m.CreateCtxFunc == nil {
+		unexpected("CreateCtx")
+	}
+	return m.CreateCtxFunc(ctx, name, contentType)
+}
+
+// List calls ListFunc, or ListCtxFunc with a background context
+func (m *Drive) List(limit int, prefix, last string) (*drive.ListOutput, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(limit, prefix, last)
+	}
+	return m.ListCtx(context.Background(), limit, prefix, last)
+}
+
+// ListCtx calls ListCtxFunc
+func (m *Drive) ListCtx(ctx context.Context, limit int, prefix, last string) (*drive.ListOutput, error) {
+	if m.ListCtxFunc == nil {
+		unexpected("ListCtx")
+	}
+	return m.ListCtxFunc(ctx, limit, prefix, last)
+}
+
+// DeleteMany calls DeleteManyFunc, or DeleteManyCtxFunc with a background context
+func (m *Drive) DeleteMany(names []string) (*drive.DeleteManyOutput, error) {
+	if m.DeleteManyFunc != nil {
+		return m.DeleteManyFunc(names)
+	}
+	return m.DeleteManyCtx(context.Background(), names)
+}
+
+// DeleteManyCtx calls DeleteManyCtxFunc
+func (m *Drive) DeleteManyCtx(ctx context.Context, names []string) (*drive.DeleteManyOutput, error) {
+	if m.DeleteManyCtxFunc == nil {
+		unexpected("DeleteManyCtx")
+	}
+	return m.DeleteManyCtxFunc(ctx, names)
+}
+
+// Delete calls DeleteFunc, or DeleteCtxFunc with a background context
+func (m *Drive) Delete(name string) (string, error) {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(name)
+	}
+	return m.DeleteCtx(context.Background(), name)
+}
+
+// DeleteCtx calls DeleteCtxFunc
+func (m *Drive) DeleteCtx(ctx context.Context, name string) (string, error) {
+	if m.DeleteCtxFunc == nil {
+		unexpected("DeleteCtx")
+	}
+	return m.DeleteCtxFunc(ctx, name)
+}
+
+// DeletePrefix calls DeletePrefixFunc, or DeletePrefixCtxFunc with a background context
+func (m *Drive) DeletePrefix(prefix string) (*drive.DeleteManyOutput, error) {
+	if m.DeletePrefixFunc != nil {
+		return m.DeletePrefixFunc(prefix)
+	}
+	return m.DeletePrefixCtx(context.Background(), prefix)
+}
+
+// DeletePrefixCtx calls DeletePrefixCtxFunc
+func (m *Drive) DeletePrefixCtx(ctx context.Context, prefix string) (*drive.DeleteManyOutput, error) {
+	if m.DeletePrefixCtxFunc == nil {
+		unexpected("DeletePrefixCtx")
+	}
+	return m.DeletePrefixCtxFunc(ctx, prefix)
+}
+
+// Sync calls SyncFunc, or SyncCtxFunc with a background context
+func (m *Drive) Sync(i *drive.SyncInput) (*drive.SyncOutput, error) {
+	if m.SyncFunc != nil {
+		return m.SyncFunc(i)
+	}
+	return m.SyncCtx(context.Background(), i)
+}
+
+// SyncCtx calls SyncCtxFunc
+func (m *Drive) SyncCtx(ctx context.Context, i *drive.SyncInput) (*drive.SyncOutput, error) {
+	if m.SyncCtxFunc == nil {
+		unexpected("SyncCtx")
+	}
+	return m.SyncCtxFunc(ctx, i)
+}
+
+// Copy calls CopyFunc, or CopyCtxFunc with a background context
+func (m *Drive) Copy(src, dst string) (string, error) {
+	if m.CopyFunc != nil {
+		return m.CopyFunc(src, dst)
+	}
+	return m.CopyCtx(context.Background(), src, dst)
+}
+
+// CopyCtx calls CopyCtxFunc
+func (m *Drive) CopyCtx(ctx context.Context, src, dst string) (string, error) {
+	if m.CopyCtxFunc == nil {
+		unexpected("CopyCtx")
+	}
+	return m.CopyCtxFunc(ctx, src, dst)
+}
+
+// CopyTo calls CopyToFunc, or CopyToCtxFunc with a background context
+func (m *Drive) CopyTo(to drive.API, src, dst string) (string, error) {
+	if m.CopyToFunc != nil {
+		return m.CopyToFunc(to, src, dst)
+	}
+	return m.CopyToCtx(context.Background(), to, src, dst)
+}
+
+// CopyToCtx calls CopyToCtxFunc
+func (m *Drive) CopyToCtx(ctx context.Context, to drive.API, src, dst string) (string, error) {
+	if m.CopyToCtxFunc == nil {
+		unexpected("CopyToCtx")
+	}
+	return m.CopyToCtxFunc(ctx, to, src, dst)
+}
+
+// Move calls MoveFunc, or MoveCtxFunc with a background context
+func (m *Drive) Move(src, dst string) (string, error) {
+	if m.MoveFunc != nil {
+		return m.MoveFunc(src, dst)
+	}
+	return m.MoveCtx(context.Background(), src, dst)
+}
+
+// MoveCtx calls MoveCtxFunc
+func (m *Drive) MoveCtx(ctx context.Context, src, dst string) (string, error) {
+	if m.MoveCtxFunc == nil {
+		unexpected("MoveCtx")
+	}
+	return m.MoveCtxFunc(ctx, src, dst)
+}
+
+// CopyPrefix calls CopyPrefixFunc, or CopyPrefixCtxFunc with a background context
+func (m *Drive) CopyPrefix(i *drive.CopyPrefixInput) (*drive.CopyPrefixOutput, error) {
+	if m.CopyPrefixFunc != nil {
+		return m.CopyPrefixFunc(i)
+	}
+	return m.CopyPrefixCtx(context.Background(), i)
+}
+
+// CopyPrefixCtx calls CopyPrefixCtxFunc
+func (m *Drive) CopyPrefixCtx(ctx context.Context, i *drive.CopyPrefixInput) (*drive.CopyPrefixOutput, error) {
+	if m.CopyPrefixCtxFunc == nil {
+		unexpected("CopyPrefixCtx")
+	}
+	return m.CopyPrefixCtxFunc(ctx, i)
+}
@@ -0,0 +1,110 @@
+package drivemock
+
+import (
+	"bytes"
+	"os"
+	"sync"
+
+	"github.com/deta/deta-go/service/drive"
+)
+
+// File is an in-memory drive.FileReader, for returning from OpenFunc and OpenCtxFunc
+//
+//	mock := &drivemock.Drive{
+//		OpenCtxFunc: func(ctx context.Context, name string) (drive.FileReader, error) {
+//			return drivemock.NewFile(name, []byte("content")), nil
+//		},
+//	}
+type File struct {
+	*bytes.Reader
+	name string
+}
+
+var _ drive.FileReader = (*File)(nil)
+
+// NewFile returns a pointer to a new File with the name and content
+func NewFile(name string, content []byte) *File {
+	return &File{Reader: bytes.NewReader(content), name: name}
+}
+
+// Name returns the name of the file
+func (f *File) Name() string {
+	return f.name
+}
+
+// Close does nothing
+func (f *File) Close() error {
+	return nil
+}
+
+// Writer is an in-memory drive.FileWriter, for returning from CreateFunc and CreateCtxFunc
+//
+// It records the written content and how it was closed, to be checked by tests.
+//
+//	w := drivemock.NewWriter("report.json")
+//	mock := &drivemock.Drive{
+//		CreateCtxFunc: func(ctx context.Context, name, contentType string) (drive.FileWriter, error) {
+//			return w, nil
+//		},
+//	}
+type Writer struct {
+	name string
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	err    error
+}
+
+var _ drive.FileWriter = (*Writer)(nil)
+
+// NewWriter returns a pointer to a new Writer with the name
+func NewWriter(name string) *Writer {
+	return &Writer{name: name}
+}
+
+// Name returns the name of the file
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Write appends p to the content, after the Writer is closed it returns os.ErrClosed
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+// Close closes the Writer
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+// CloseWithError closes the Writer, recording err as the reason of the abort
+func (w *Writer) CloseWithError(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.err = err
+	return nil
+}
+
+// Content returns the written content
+func (w *Writer) Content() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]byte(nil), w.buf.Bytes()...)
+}
+
+// Closed returns whether the Writer is closed, and the error of CloseWithError if it was aborted
+func (w *Writer) Closed() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed, w.err
+}
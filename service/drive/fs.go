@@ -0,0 +1,339 @@
+//go:build go1.16
+// +build go1.16
+
+package drive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// FS is a read-only fs.FS of the files of a Drive.
+//
+// Slashes in the names of files separate directories, directories are synthesized from
+// the names of the files they contain. If a file has the same name as a directory, like
+// 'a' and 'a/b', the file is listed and opened under the name and the directory is hidden.
+//
+//	drawings, err := drive.New(d, "drawings")
+//	if err != nil {
+//		return err
+//	}
+//	http.Handle("/drawings/", http.StripPrefix("/drawings/", http.FileServer(http.FS(drive.NewFS(drawings)))))
+type FS struct {
+	d   *Drive
+	ctx context.Context
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+)
+
+// NewFS returns a pointer to a new FS of the files of the Drive
+func NewFS(d *Drive) *FS {
+	return NewFSCtx(context.Background(), d)
+}
+
+// NewFSCtx is like NewFS but uses the provided context for the requests of the FS.
+func NewFSCtx(ctx context.Context, d *Drive) *FS {
+	return &FS{d: d, ctx: ctx}
+}
+
+// returns a *fs.PathError for the error of a drive operation
+func pathError(op, name string, err error) error {
+	if errors.Is(err, deta.ErrNotFound) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// Open opens the file or directory with the name.
+//
+// Files implement io.Seeker and io.ReaderAt, except for files of unknown size which are
+// only read as a stream.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name != "." {
+		info, err := fsys.d.StatCtx(fsys.ctx, name)
+		if err == nil {
+			if info.Size < 0 {
+				return &fsStream{fsys: fsys, info: info}, nil
+			}
+			return &fsFile{File: fsys.d.newFile(fsys.ctx, info), info: info}, nil
+		}
+		if !errors.Is(err, deta.ErrNotFound) {
+			return nil, pathError("open", name, err)
+		}
+	}
+
+	entries, err := fsys.readDir(name)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, pathError("open", name, fs.ErrNotExist)
+	}
+	return &fsDir{name: name, entries: entries}, nil
+}
+
+// ReadDir reads the directory with the name and returns its entries sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := fsys.readDir(name)
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, pathError("readdir", name, fs.ErrNotExist)
+	}
+	return entries, nil
+}
+
+// Stat returns the fs.FileInfo of the file or directory with the name.
+//
+// The Sys method of the fs.FileInfo of a file returns its *FileInfo.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: errors.Unwrap(err)}
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// ReadFile reads the whole file with the name.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	rc, err := fsys.d.GetCtx(fsys.ctx, name)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// lists the entries of a directory, sorted by name
+func (fsys *FS) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	var entries []fs.DirEntry
+	// names of the entries, a file replaces a directory of the same name
+	seen := make(map[string]*fsDirEntry)
+	err := fsys.d.listPages(fsys.ctx, prefix, func(names []string) error {
+		for _, n := range names {
+			rest := strings.TrimPrefix(n, prefix)
+			elem := rest
+			isDir := false
+			if i := strings.Index(rest, "/"); i >= 0 {
+				elem, isDir = rest[:i], true
+			}
+			if elem == "" {
+				continue
+			}
+			if e, ok := seen[elem]; ok {
+				if e.dir && !isDir {
+					e.path, e.dir = n, false
+				}
+				continue
+			}
+			e := &fsDirEntry{fsys: fsys, name: elem, path: n}
+			if isDir {
+				e.path, e.dir = prefix+elem, true
+			}
+			seen[elem] = e
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// an fs.FileInfo of a file or a synthesized directory
+type fsFileInfo struct {
+	name string
+	info *FileInfo
+}
+
+func (fi *fsFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fsFileInfo) Size() int64 {
+	if fi.info == nil {
+		return 0
+	}
+	return fi.info.Size
+}
+
+func (fi *fsFileInfo) Mode() fs.FileMode {
+	if fi.info == nil {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi *fsFileInfo) ModTime() time.Time {
+	if fi.info == nil {
+		return time.Time{}
+	}
+	return fi.info.LastModified
+}
+
+func (fi *fsFileInfo) IsDir() bool {
+	return fi.info == nil
+}
+
+func (fi *fsFileInfo) Sys() interface{} {
+	if fi.info == nil {
+		return nil
+	}
+	return fi.info
+}
+
+// an fs.DirEntry of a file or a synthesized directory
+type fsDirEntry struct {
+	fsys *FS
+	name string
+	path string
+	dir  bool
+}
+
+func (e *fsDirEntry) Name() string {
+	return e.name
+}
+
+func (e *fsDirEntry) IsDir() bool {
+	return e.dir
+}
+
+func (e *fsDirEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e *fsDirEntry) Info() (fs.FileInfo, error) {
+	if e.dir {
+		return &fsFileInfo{name: e.name}, nil
+	}
+	info, err := e.fsys.d.StatCtx(e.fsys.ctx, e.path)
+	if err != nil {
+		return nil, pathError("stat", e.path, err)
+	}
+	return &fsFileInfo{name: e.name, info: info}, nil
+}
+
+// an fs.File of a file
+type fsFile struct {
+	*File
+	info *FileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{name: path.Base(f.info.Name), info: f.info}, nil
+}
+
+// an fs.File of a file of unknown size, downloaded on the first read
+type fsStream struct {
+	fsys   *FS
+	info   *FileInfo
+	rc     io.ReadCloser
+	closed bool
+}
+
+func (f *fsStream) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{name: path.Base(f.info.Name), info: f.info}, nil
+}
+
+func (f *fsStream) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name, Err: fs.ErrClosed}
+	}
+	if f.rc == nil {
+		rc, err := f.fsys.d.GetCtx(f.fsys.ctx, f.info.Name)
+		if err != nil {
+			return 0, pathError("read", f.info.Name, err)
+		}
+		f.rc = rc
+	}
+	return f.rc.Read(p)
+}
+
+func (f *fsStream) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.info.Name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.rc == nil {
+		return nil
+	}
+	return f.rc.Close()
+}
+
+// an fs.ReadDirFile of a synthesized directory
+type fsDir struct {
+	name    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{name: path.Base(d.name)}, nil
+}
+
+func (d *fsDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	left := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return left, nil
+	}
+	if len(left) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(left) {
+		n = len(left)
+	}
+	d.offset += n
+	return left[:n], nil
+}
@@ -0,0 +1,128 @@
+//go:build go1.16
+// +build go1.16
+
+package drive
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/deta/deta-go/deta"
+)
+
+func TestFS(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	files := map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "bb",
+		"dir/sub/c.txt": "ccc",
+	}
+	for name, content := range files {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(content)}); err != nil {
+			t.Fatalf("Failed to put file %s: %v", name, err)
+		}
+	}
+
+	fsys := NewFS(drive)
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	var walked []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk the fs: %v", err)
+	}
+	expected := []string{".", "a.txt", "dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt"}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Walked paths not as expected. Expected: %v Got: %v", expected, walked)
+	}
+
+	content, err := fs.ReadFile(fsys, "dir/sub/c.txt")
+	if err != nil || string(content) != "ccc" {
+		t.Errorf("Read content not as expected. Expected: %q Got: %q (error: %v)", "ccc", content, err)
+	}
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", fs.ErrNotExist, err)
+	}
+	if _, err := fsys.Stat("/a.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Unexpected error value. Expected: %v Got: %v", fs.ErrInvalid, err)
+	}
+}
+
+func TestFSUnknownSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.Method == "HEAD" {
+			return
+		}
+		// stream the whole file without its length
+		w.Write([]byte("con"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("tent"))
+	}))
+	defer srv.Close()
+
+	d, _ := deta.New(deta.WithProjectKey("project_key"), deta.WithDriveRootEndpoint(srv.URL))
+	drive, _ := New(d, "drive")
+
+	f, err := NewFS(drive).Open("file")
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.Size() != -1 {
+		t.Errorf("Unexpected size. Expected: %d Got: %v (error: %v)", -1, fi, err)
+	}
+	content, err := ioutil.ReadAll(f)
+	if err != nil || string(content) != "content" {
+		t.Errorf("Read content not as expected. Expected: %q Got: %q (error: %v)", "content", content, err)
+	}
+}
+
+func TestFSFileAndDirectory(t *testing.T) {
+	drive := SetupDrive()
+	defer TearDownDrive(drive, t)
+
+	// the file 'a' has the name of the directory of 'a/b'
+	for _, name := range []string{"a", "a/b", "c/d"} {
+		if _, err := drive.Put(&PutInput{Name: name, Body: strings.NewReader(name)}); err != nil {
+			t.Fatalf("Failed to put file %s: %v", name, err)
+		}
+	}
+
+	fsys := NewFS(drive)
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+		if e.Name() == "a" && e.IsDir() {
+			t.Errorf("Entry 'a' is a directory, expected the file")
+		}
+	}
+	if expected := []string{"a", "c"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Entries not as expected. Expected: %v Got: %v", expected, names)
+	}
+	if err := fstest.TestFS(fsys, "a", "c/d"); err != nil {
+		t.Fatal(err)
+	}
+}
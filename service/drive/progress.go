@@ -0,0 +1,132 @@
+package drive
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Progress is the progress of an upload or a download
+type Progress struct {
+	// number of bytes transferred so far
+	Bytes int64
+	// total number of bytes, -1 if unknown
+	Total int64
+	// number of the part transferred last, 0 for downloads
+	Part int
+	// average number of bytes transferred per second
+	Throughput float64
+}
+
+// ProgressFunc is called with the progress of a transfer.
+//
+// Calls are serialized, even if parts are uploaded concurrently.
+type ProgressFunc func(p Progress)
+
+// reports the progress of a transfer to a ProgressFunc
+type progressTracker struct {
+	mu      sync.Mutex
+	fn      ProgressFunc
+	start   time.Time
+	initial int64
+	bytes   int64
+	total   int64
+}
+
+// returns a new progressTracker, or nil if fn is nil
+//
+// The initial bytes have been transferred before, they do not count towards the throughput.
+func newProgressTracker(fn ProgressFunc, initial, total int64) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{
+		fn:      fn,
+		start:   time.Now(),
+		initial: initial,
+		bytes:   initial,
+		total:   total,
+	}
+}
+
+// adds n transferred bytes of the part and reports the progress
+func (t *progressTracker) add(n int64, part int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.bytes += n
+	p := Progress{
+		Bytes: t.bytes,
+		Total: t.total,
+		Part:  part,
+	}
+	if elapsed := time.Since(t.start).Seconds(); elapsed > 0 {
+		p.Throughput = float64(t.bytes-t.initial) / elapsed
+	}
+	t.fn(p)
+}
+
+// returns the number of bytes left in the reader, or -1 if unknown
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := v.Seek(cur, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - cur
+	default:
+		return -1
+	}
+}
+
+// reports the progress of reads from a download
+type progressReader struct {
+	io.ReadCloser
+	t *progressTracker
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.ReadCloser.Read(p)
+	if n > 0 {
+		pr.t.add(int64(n), 0)
+	}
+	return n, err
+}
+
+// GetWithProgress is like Get but reports the progress of reading the file to the ProgressFunc.
+func (d *Drive) GetWithProgress(name string, fn ProgressFunc) (io.ReadCloser, error) {
+	return d.GetWithProgressCtx(context.Background(), name, fn)
+}
+
+// GetWithProgressCtx is like GetWithProgress but uses the provided context for the request.
+func (d *Drive) GetWithProgressCtx(ctx context.Context, name string, fn ProgressFunc) (io.ReadCloser, error) {
+	o, err := d.get(ctx, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	total := int64(-1)
+	if n, err := strconv.ParseInt(o.Header.Get("Content-Length"), 10, 64); err == nil {
+		total = n
+	}
+	t := newProgressTracker(fn, 0, total)
+	if t == nil {
+		return o.BodyReadCloser, nil
+	}
+	return &progressReader{ReadCloser: o.BodyReadCloser, t: t}, nil
+}
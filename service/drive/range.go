@@ -0,0 +1,287 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// GetRange gets length bytes of a file from the Drive, starting at offset.
+//
+// A negative length gets the rest of the file.
+// Returns a io.ReadCloser for the range, an error matching deta.ErrRangeNotSatisfiable is
+// returned if the offset is beyond the end of the file.
+func (d *Drive) GetRange(name string, offset, length int64) (io.ReadCloser, error) {
+	return d.GetRangeCtx(context.Background(), name, offset, length)
+}
+
+// GetRangeCtx is like GetRange but uses the provided context for the request.
+//
+// The context also governs reading from the returned io.ReadCloser.
+func (d *Drive) GetRangeCtx(ctx context.Context, name string, offset, length int64) (io.ReadCloser, error) {
+	if name == "" {
+		return nil, deta.ErrEmptyName
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: negative offset", deta.ErrBadOffset)
+	}
+	if length == 0 {
+		// an empty range of a missing file or beyond the end of the file is still an error
+		fi, err := d.StatCtx(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Size >= 0 && offset > fi.Size {
+			return nil, deta.ErrRangeNotSatisfiable
+		}
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+
+	r := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		r = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+	o, err := d.get(ctx, name, map[string]string{"Range": r})
+	if err != nil {
+		return nil, err
+	}
+	if o.Status == http.StatusPartialContent {
+		return o.BodyReadCloser, nil
+	}
+
+	// the range was ignored, skip to the offset of the whole file
+	body := o.BodyReadCloser
+	if _, err := io.CopyN(ioutil.Discard, body, offset); err != nil {
+		body.Close()
+		if err == io.EOF {
+			return nil, deta.ErrRangeNotSatisfiable
+		}
+		return nil, err
+	}
+	if length < 0 {
+		return body, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(body, length), Closer: body}, nil
+}
+
+// an io.ReadCloser reading from a limited reader
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// parses the total size of a file from a Content-Range header
+func parseContentRangeSize(h http.Header) (int64, bool) {
+	cr := h.Get("Content-Range")
+	n := strings.LastIndex(cr, "/")
+	if !strings.HasPrefix(cr, "bytes ") || n < 0 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(cr[n+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+// FileReader is a read-only handle of a file in a Drive, returned by Open
+type FileReader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	// Name returns the name of the file.
+	Name() string
+	// Size returns the size of the file in bytes.
+	Size() int64
+}
+
+// File is a read-only handle of a file in a Drive, reading the file with ranged requests.
+//
+// File implements FileReader. ReadAt can be called concurrently, while Read and Seek share
+// the offset of the handle.
+//
+//	f, err := videos.Open("intro.mp4")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	http.ServeContent(w, r, f.Name(), time.Time{}, f)
+type File struct {
+	d    *Drive
+	ctx  context.Context
+	name string
+	size int64
+
+	mu     sync.Mutex
+	offset int64
+	// stream reading the file from offset, nil if not open
+	body   io.ReadCloser
+	closed bool
+}
+
+// Open opens a file of the Drive for reading.
+//
+// The returned FileReader is a *File.
+func (d *Drive) Open(name string) (FileReader, error) {
+	return d.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx is like Open but uses the provided context for the requests of the File.
+func (d *Drive) OpenCtx(ctx context.Context, name string) (FileReader, error) {
+	if name == "" {
+		return nil, deta.ErrEmptyName
+	}
+	info, err := d.StatCtx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size < 0 {
+		return nil, fmt.Errorf("failed to open %s: the size of the file is unknown", name)
+	}
+	return d.newFile(ctx, info), nil
+}
+
+// returns a new handle of the file
+func (d *Drive) newFile(ctx context.Context, info *FileInfo) *File {
+	return &File{
+		d:    d,
+		ctx:  ctx,
+		name: info.Name,
+		size: info.Size,
+	}
+}
+
+// Name returns the name of the file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Size returns the size of the file when it was opened.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Read reads from the file at the offset of the handle.
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if f.body == nil {
+		body, err := f.d.GetRangeCtx(f.ctx, f.name, f.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	if err == io.EOF {
+		f.body.Close()
+		f.body = nil
+		if f.offset < f.size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the file starting at offset off.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+
+	if closed {
+		return 0, os.ErrClosed
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("%w: negative offset", deta.ErrBadOffset)
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	length := int64(len(p))
+	if left := f.size - off; left < length {
+		length = left
+	}
+	body, err := f.d.GetRangeCtx(f.ctx, f.name, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.ReadFull(body, p[:length])
+	if err == nil && int(length) < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek sets the offset of the handle for the next Read.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("%w: invalid whence", deta.ErrBadOffset)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: negative offset", deta.ErrBadOffset)
+	}
+
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Close closes the handle.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	if f.body != nil {
+		err := f.body.Close()
+		f.body = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// UploadSession is the state of a chunked upload.
+//
+// A session can be serialized to json, to resume the upload with Upload after the process restarts.
+//
+//	s, err := drawings.StartUpload("art.svg", "image/svg+xml")
+//	if err != nil {
+//		return err
+//	}
+//	store := drive.NewFileUploadStore("uploads")
+//	_, err = drawings.Upload(&drive.UploadInput{Session: s, Body: f, Store: store})
+//
+//	// later, after a failure
+//	s, err = store.Load("art.svg")
+//	if err != nil {
+//		return err
+//	}
+//	_, err = drawings.Upload(&drive.UploadInput{Session: s, Body: f, Store: store})
+type UploadSession struct {
+	// name of file
+	Name string `json:"name"`
+	// id of the upload
+	UploadID string `json:"upload_id"`
+	// content type of file
+	ContentType string `json:"content_type,omitempty"`
+	// size of the parts, the last part may be smaller
+	PartSize int64 `json:"part_size"`
+	// numbers of the uploaded parts, in ascending order
+	Completed []int `json:"completed"`
+
+	mu sync.Mutex
+}
+
+// returns whether the part has been uploaded
+func (s *UploadSession) completed(part int) bool {
+	n := sort.SearchInts(s.Completed, part)
+	return n < len(s.Completed) && s.Completed[n] == part
+}
+
+// marks the part as uploaded
+func (s *UploadSession) complete(part int) {
+	if s.completed(part) {
+		return
+	}
+	s.Completed = append(s.Completed, part)
+	sort.Ints(s.Completed)
+}
+
+// UploadStore persists upload sessions by the name of their file
+//
+// Package basestore provides an UploadStore storing the sessions in a Base.
+type UploadStore interface {
+	// Save saves the session, replacing a previous session of the same file
+	Save(s *UploadSession) error
+	// Load loads the session of the file, an error matching deta.ErrNotFound is returned if there is none
+	Load(name string) (*UploadSession, error)
+	// Delete deletes the session of the file, if any
+	Delete(name string) error
+}
+
+// FileUploadStore stores upload sessions as json files in a directory
+type FileUploadStore struct {
+	dir string
+}
+
+// NewFileUploadStore returns a pointer to a new FileUploadStore storing the sessions in the directory
+//
+// The directory is created when the first session is saved, if it does not exist.
+func NewFileUploadStore(dir string) *FileUploadStore {
+	return &FileUploadStore{dir: dir}
+}
+
+// returns the path of the session file of the file name
+func (fs *FileUploadStore) path(name string) string {
+	return filepath.Join(fs.dir, url.QueryEscape(name)+".json")
+}
+
+// Save writes the session to its file
+func (fs *FileUploadStore) Save(s *UploadSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fs.dir, 0755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a crash does not leave a partial session
+	f, err := ioutil.TempFile(fs.dir, ".upload-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fs.path(s.Name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Load reads the session from its file
+func (fs *FileUploadStore) Load(name string) (*UploadSession, error) {
+	data, err := ioutil.ReadFile(fs.path(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: no upload session for '%s'", deta.ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s UploadSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Delete removes the file of the session
+func (fs *FileUploadStore) Delete(name string) error {
+	err := os.Remove(fs.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// StartUpload starts a chunked upload of a file and returns its session.
+//
+// Upload the content of the file with Upload.
+func (d *Drive) StartUpload(name, contentType string) (*UploadSession, error) {
+	return d.StartUploadCtx(context.Background(), name, contentType)
+}
+
+// StartUploadCtx is like StartUpload but uses the provided context for the request.
+func (d *Drive) StartUploadCtx(ctx context.Context, name, contentType string) (*UploadSession, error) {
+	if name == "" {
+		return nil, deta.ErrEmptyName
+	}
+	uploadId, err := d.startUpload(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadSession{
+		Name:        name,
+		UploadID:    uploadId,
+		ContentType: contentType,
+		PartSize:    uploadChunkSize,
+		Completed:   []int{},
+	}, nil
+}
+
+// UploadInput input for Upload operation.
+type UploadInput struct {
+	// session of the upload
+	Session *UploadSession
+	// content of the whole file, parts are read at their offset
+	//
+	// Use io.NewSectionReader to upload from an io.ReaderAt.
+	Body io.ReadSeeker
+	// store to save the session to after each uploaded part, and to delete it from once the upload is finished
+	Store UploadStore
+	// maximum number of parts uploaded concurrently, parts are uploaded one after another if not set
+	Concurrency int
+	// number of times a failed part is retried, failed parts are not retried if not set
+	PartRetries int
+	// called after each uploaded part with the progress of the upload, if set
+	//
+	// Parts uploaded before count towards the bytes transferred.
+	OnProgress ProgressFunc
+}
+
+// Upload uploads the parts of the session that have not been uploaded yet and finishes the upload.
+//
+// The upload is not aborted if it fails, the session keeps track of the uploaded parts to resume
+// the upload later with the same content. Use AbortUpload to abort the upload instead.
+// Returns the name of file that was put in the drive.
+func (d *Drive) Upload(i *UploadInput) (string, error) {
+	return d.UploadCtx(context.Background(), i)
+}
+
+// UploadCtx is like Upload but uses the provided context for the requests.
+func (d *Drive) UploadCtx(ctx context.Context, i *UploadInput) (string, error) {
+	s := i.Session
+	if s == nil || s.UploadID == "" || s.PartSize <= 0 {
+		return "", deta.ErrBadUploadSession
+	}
+	if s.Name == "" {
+		return "", deta.ErrEmptyName
+	}
+	if i.Body == nil {
+		return "", deta.ErrEmptyData
+	}
+
+	size, err := i.Body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	parts := int((size + s.PartSize - 1) / s.PartSize)
+	if parts == 0 {
+		// an empty file is uploaded as a single empty part
+		parts = 1
+	}
+
+	// size of the parts uploaded before
+	var uploaded int64
+	s.mu.Lock()
+	for _, part := range s.Completed {
+		if offset := int64(part-1) * s.PartSize; offset < size {
+			uploaded += min64(s.PartSize, size-offset)
+		}
+	}
+	s.mu.Unlock()
+
+	part := 0
+	err = d.uploadParts(ctx, &partUpload{
+		name:        s.Name,
+		uploadId:    s.UploadID,
+		contentType: s.ContentType,
+		concurrency: i.Concurrency,
+		retries:     i.PartRetries,
+		progress:    newProgressTracker(i.OnProgress, uploaded, size),
+		next: func() (uploadJob, error) {
+			for part++; part <= parts; part++ {
+				s.mu.Lock()
+				done := s.completed(part)
+				s.mu.Unlock()
+				if done {
+					continue
+				}
+
+				offset := int64(part-1) * s.PartSize
+				chunk := make([]byte, min64(s.PartSize, size-offset))
+				if _, err := i.Body.Seek(offset, io.SeekStart); err != nil {
+					return uploadJob{}, err
+				}
+				if _, err := io.ReadFull(i.Body, chunk); err != nil {
+					return uploadJob{}, err
+				}
+				return uploadJob{part: part, chunk: chunk}, nil
+			}
+			return uploadJob{}, io.EOF
+		},
+		done: func(part int) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.complete(part)
+			if i.Store == nil {
+				return nil
+			}
+			return i.Store.Save(s)
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.finishUpload(ctx, s.Name, s.UploadID); err != nil {
+		return "", err
+	}
+	if i.Store != nil {
+		if err := i.Store.Delete(s.Name); err != nil {
+			return "", err
+		}
+	}
+	return s.Name, nil
+}
+
+// AbortUpload aborts the upload of the session.
+func (d *Drive) AbortUpload(s *UploadSession) error {
+	return d.AbortUploadCtx(context.Background(), s)
+}
+
+// AbortUploadCtx is like AbortUpload but uses the provided context for the request.
+func (d *Drive) AbortUploadCtx(ctx context.Context, s *UploadSession) error {
+	if s == nil || s.UploadID == "" {
+		return deta.ErrBadUploadSession
+	}
+	return d.abortUploadCtx(ctx, s.Name, s.UploadID)
+}
+
+// returns the smaller of a and b
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
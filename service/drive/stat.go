@@ -0,0 +1,122 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deta/deta-go/deta"
+	"github.com/deta/deta-go/internal/client"
+)
+
+// FileInfo is the metadata of a file in a Drive
+type FileInfo struct {
+	// name of file
+	Name string
+	// size of file in bytes, -1 if the Drive does not report it
+	Size int64
+	// content type of file
+	ContentType string
+	// time the file was last modified, zero if unknown
+	LastModified time.Time
+	// entity tag of the content of file, empty if unknown
+	ETag string
+}
+
+// Stat gets the metadata of a file from the Drive, without downloading the file.
+//
+// Returns an error matching deta.ErrNotFound if the file does not exist. The size is -1 if
+// the Drive does not report the size of the file.
+func (d *Drive) Stat(name string) (*FileInfo, error) {
+	return d.StatCtx(context.Background(), name)
+}
+
+// StatCtx is like Stat but uses the provided context for the request.
+func (d *Drive) StatCtx(ctx context.Context, name string) (*FileInfo, error) {
+	// only request the first byte of the file, the size is in the content range
+	o, err := d.get(ctx, name, map[string]string{"Range": "bytes=0-0"})
+
+	var apiErr *deta.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// the first byte of an empty file is not satisfiable, the whole file is empty
+		o, err = d.get(ctx, name, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.BodyReadCloser.Close()
+
+	fi := fileInfo(name, o)
+	if fi.Size < 0 {
+		// the whole file was sent without its size, ask for the headers only
+		if ho, err := d.head(ctx, name); err == nil {
+			if size := contentLength(ho); size >= 0 {
+				fi.Size = size
+			}
+		}
+	}
+	return fi, nil
+}
+
+// requests the headers of a download of a file
+func (d *Drive) head(ctx context.Context, name string) (*client.RequestOutput, error) {
+	return d.client.Request(&client.RequestInput{
+		Context:     ctx,
+		Path:        "/files/download",
+		QueryParams: map[string]string{"name": name},
+		Method:      "HEAD",
+		Idempotent:  true,
+	})
+}
+
+// returns the content length of the response, -1 if it is unknown
+func contentLength(o *client.RequestOutput) int64 {
+	size, err := strconv.ParseInt(o.Header.Get("Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// returns the metadata of a file from the headers of a download
+//
+// The size is -1 if the whole file was sent without a content length.
+func fileInfo(name string, o *client.RequestOutput) *FileInfo {
+	fi := &FileInfo{
+		Name:        name,
+		ContentType: o.Header.Get("Content-Type"),
+		ETag:        o.Header.Get("ETag"),
+	}
+	if lm := o.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			fi.LastModified = t
+		}
+	}
+
+	if size, ok := parseContentRangeSize(o.Header); ok && o.Status == http.StatusPartialContent {
+		fi.Size = size
+		return fi
+	}
+	// the whole file was sent
+	fi.Size = contentLength(o)
+	return fi
+}
+
+// Exists reports whether a file exists in the Drive.
+func (d *Drive) Exists(name string) (bool, error) {
+	return d.ExistsCtx(context.Background(), name)
+}
+
+// ExistsCtx is like Exists but uses the provided context for the request.
+func (d *Drive) ExistsCtx(ctx context.Context, name string) (bool, error) {
+	_, err := d.StatCtx(ctx, name)
+	if errors.Is(err, deta.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
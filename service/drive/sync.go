@@ -0,0 +1,545 @@
+package drive
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// default number of files synced concurrently
+const defaultSyncConcurrency = 4
+
+// SyncDirection is the direction of a Sync operation
+type SyncDirection int
+
+const (
+	// SyncUpload syncs the files of the local directory to the Drive
+	SyncUpload SyncDirection = iota
+	// SyncDownload syncs the files of the Drive to the local directory
+	SyncDownload
+)
+
+// SyncOp is an operation on a file in the destination of a Sync operation
+type SyncOp int
+
+const (
+	// SyncCreate creates a file missing in the destination
+	SyncCreate SyncOp = iota
+	// SyncUpdate replaces a file that changed in the source
+	SyncUpdate
+	// SyncDelete deletes a file that is missing in the source
+	SyncDelete
+)
+
+func (op SyncOp) String() string {
+	switch op {
+	case SyncCreate:
+		return "create"
+	case SyncUpdate:
+		return "update"
+	case SyncDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("SyncOp(%d)", int(op))
+	}
+}
+
+// SyncInput input for Sync operation.
+type SyncInput struct {
+	// local directory
+	LocalDir string
+	// prefix of the names of the files in the Drive, the directory of the files if it does not end with a '/'
+	//
+	// The name of a file is the prefix followed by its slash-separated path relative to the local directory.
+	Prefix string
+	// direction of the sync, uploads by default
+	Direction SyncDirection
+	// delete the files in the destination that are missing in the source
+	Delete bool
+	// only sync the files matching one of the glob patterns, all files if empty
+	//
+	// Patterns use the syntax of path.Match and match the relative path of a file,
+	// patterns without a '/' also match the base name of a file.
+	Include []string
+	// do not sync the files matching one of the glob patterns
+	Exclude []string
+	// only report the operations without changing any file
+	DryRun bool
+	// maximum number of files synced concurrently, 4 if not set
+	Concurrency int
+}
+
+// SyncAction is an operation on a file in the destination of a Sync operation
+type SyncAction struct {
+	// operation on the file
+	Op SyncOp
+	// slash-separated path of the file relative to the local directory
+	Path string
+	// size of the file in the source, 0 for deletes
+	Size int64
+	// error of the operation, if it failed
+	Err error
+}
+
+// SyncOutput output for Sync operation.
+type SyncOutput struct {
+	// operations on the files in the destination, sorted by path
+	Actions []*SyncAction
+	// number of files already in sync
+	Unchanged int
+}
+
+// a file in the source or destination of a sync
+type syncFile struct {
+	// local path or name in the Drive
+	loc  string
+	size int64
+}
+
+// Sync syncs the files of a local directory with the files of the Drive under a prefix.
+//
+// Files are compared by size, and files of the same size by the md5 hash of their content. The
+// hash is taken from the etag of single part files, other files of the same size are downloaded
+// to be hashed. New and changed files are copied from the source to the destination.
+// Returns the operations on the files in the destination, performed unless DryRun is set.
+// If some operations failed, the output is returned together with an error.
+func (d *Drive) Sync(i *SyncInput) (*SyncOutput, error) {
+	return d.SyncCtx(context.Background(), i)
+}
+
+// SyncCtx is like Sync but uses the provided context for the requests.
+func (d *Drive) SyncCtx(ctx context.Context, i *SyncInput) (*SyncOutput, error) {
+	if i.LocalDir == "" {
+		return nil, fmt.Errorf("%w: no local directory", deta.ErrBadSyncInput)
+	}
+	if i.Direction == SyncUpload {
+		// a missing source directory would delete every file with Delete
+		info, err := os.Stat(i.LocalDir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%w: %s is not a directory", deta.ErrBadSyncInput, i.LocalDir)
+		}
+	}
+	prefix := i.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	for _, pattern := range append(append([]string{}, i.Include...), i.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: bad pattern '%s': %v", deta.ErrBadSyncInput, pattern, err)
+		}
+	}
+
+	local, err := localSyncFiles(i.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := d.remoteSyncFiles(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	src, dst := local, remote
+	if i.Direction == SyncDownload {
+		src, dst = remote, local
+	}
+	for rel := range src {
+		if !i.matches(rel) {
+			delete(src, rel)
+		}
+	}
+	for rel := range dst {
+		if !i.matches(rel) {
+			delete(dst, rel)
+		}
+	}
+
+	s := &syncer{d: d, i: i, prefix: prefix}
+	out := &SyncOutput{Actions: make([]*SyncAction, 0)}
+
+	// copy new and changed files
+	rels := make([]string, 0, len(src))
+	for rel := range src {
+		rels = append(rels, rel)
+	}
+	actions := make([]*SyncAction, len(rels))
+	concurrency := i.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultSyncConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for n, rel := range rels {
+		// wait for a free slot, do not start new copies once the context is done
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for m, rel := range rels[n:] {
+				op := SyncCreate
+				if dst[rel] != nil {
+					op = SyncUpdate
+				}
+				actions[n+m] = &SyncAction{Op: op, Path: rel, Err: err}
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(n int, rel string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			actions[n] = s.copy(ctx, rel, src[rel], dst[rel])
+		}(n, rel)
+	}
+	wg.Wait()
+	for _, a := range actions {
+		if a == nil {
+			out.Unchanged++
+			continue
+		}
+		out.Actions = append(out.Actions, a)
+	}
+
+	// delete orphans
+	if i.Delete {
+		var orphans []string
+		for rel := range dst {
+			if _, ok := src[rel]; !ok {
+				orphans = append(orphans, rel)
+			}
+		}
+		sort.Strings(orphans)
+		out.Actions = append(out.Actions, s.delete(ctx, orphans, dst)...)
+	}
+
+	sort.Slice(out.Actions, func(a, b int) bool {
+		return out.Actions[a].Path < out.Actions[b].Path
+	})
+
+	failed := 0
+	var firstErr error
+	for _, a := range out.Actions {
+		if a.Err != nil {
+			if firstErr == nil {
+				firstErr = a.Err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return out, fmt.Errorf("%d sync operation(s) failed: %w", failed, firstErr)
+	}
+	return out, nil
+}
+
+// reports whether the relative path matches the include and exclude patterns
+func (i *SyncInput) matches(rel string) bool {
+	match := func(pattern string) bool {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			ok, _ := path.Match(pattern, path.Base(rel))
+			return ok
+		}
+		return false
+	}
+
+	included := len(i.Include) == 0
+	for _, pattern := range i.Include {
+		if match(pattern) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range i.Exclude {
+		if match(pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the regular files in the directory by their slash-separated relative path
+func localSyncFiles(dir string) (map[string]*syncFile, error) {
+	files := make(map[string]*syncFile)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// a missing directory has no files
+			if p == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = &syncFile{loc: p, size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// returns the files of the Drive under the prefix by their path relative to the prefix
+//
+// The sizes of the files are not known.
+func (d *Drive) remoteSyncFiles(ctx context.Context, prefix string) (map[string]*syncFile, error) {
+	files := make(map[string]*syncFile)
+	err := d.listPages(ctx, prefix, func(names []string) error {
+		for _, name := range names {
+			rel := strings.TrimPrefix(name, prefix)
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				continue
+			}
+			files[rel] = &syncFile{loc: name, size: -1}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// performs the operations of a Sync operation
+type syncer struct {
+	d      *Drive
+	i      *SyncInput
+	prefix string
+}
+
+// returns the local and remote file of a relative path
+func (s *syncer) localRemote(src, dst *syncFile) (*syncFile, *syncFile) {
+	if s.i.Direction == SyncDownload {
+		return dst, src
+	}
+	return src, dst
+}
+
+// copies the file from the source to the destination if it is missing or changed
+//
+// Returns nil if the file is already in sync.
+func (s *syncer) copy(ctx context.Context, rel string, src, dst *syncFile) *SyncAction {
+	local, remote := s.localRemote(src, dst)
+
+	a := &SyncAction{Op: SyncCreate, Path: rel}
+	if dst != nil {
+		a.Op = SyncUpdate
+		same, err := s.same(ctx, local, remote)
+		if err != nil {
+			a.Err = err
+			return a
+		}
+		if same {
+			return nil
+		}
+	}
+	if src.size < 0 {
+		// the size of a remote source is only known once it is compared
+		fi, err := s.d.StatCtx(ctx, src.loc)
+		if err != nil {
+			a.Err = err
+			return a
+		}
+		src.size = fi.Size
+	}
+	a.Size = src.size
+
+	var loc string
+	if s.i.Direction == SyncDownload {
+		var err error
+		if loc, err = s.localPath(rel); err != nil {
+			a.Err = err
+			return a
+		}
+	}
+	if s.i.DryRun {
+		return a
+	}
+	if s.i.Direction == SyncDownload {
+		a.Err = s.download(ctx, src.loc, loc)
+	} else {
+		a.Err = s.upload(ctx, s.prefix+rel, src.loc)
+	}
+	return a
+}
+
+// returns the local path of the relative path of a remote file
+//
+// Returns an error if the path is not under the local directory.
+func (s *syncer) localPath(rel string) (string, error) {
+	clean := path.Clean(rel)
+	if path.IsAbs(clean) || filepath.IsAbs(filepath.FromSlash(rel)) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %s is not under the local directory", deta.ErrUnsafeName, rel)
+	}
+	dir := filepath.Clean(s.i.LocalDir)
+	loc := filepath.Join(dir, filepath.FromSlash(clean))
+	if r, err := filepath.Rel(dir, loc); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s is not under the local directory", deta.ErrUnsafeName, rel)
+	}
+	return loc, nil
+}
+
+// reports whether the local and remote files have the same content
+func (s *syncer) same(ctx context.Context, local, remote *syncFile) (bool, error) {
+	fi, err := s.d.StatCtx(ctx, remote.loc)
+	if err != nil {
+		return false, err
+	}
+	remote.size = fi.Size
+	// files of unknown size are compared by hash
+	if fi.Size >= 0 && fi.Size != local.size {
+		return false, nil
+	}
+
+	f, err := os.Open(local.loc)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	localHash := hex.EncodeToString(h.Sum(nil))
+
+	// the etag is the md5 hash of the content of single part files, other files are hashed
+	etag := strings.ToLower(strings.Trim(fi.ETag, `"`))
+	if _, err := hex.DecodeString(etag); err == nil && len(etag) == md5.Size*2 {
+		return localHash == etag, nil
+	}
+	remoteHash, err := s.remoteHash(ctx, remote.loc)
+	if err != nil {
+		return false, err
+	}
+	return localHash == remoteHash, nil
+}
+
+// downloads the file of the Drive and returns its md5 hash
+func (s *syncer) remoteHash(ctx context.Context, name string) (string, error) {
+	rc, err := s.d.GetCtx(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploads the local file to the Drive
+func (s *syncer) upload(ctx context.Context, name, loc string) error {
+	f, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = s.d.PutCtx(ctx, &PutInput{
+		Name:        name,
+		Body:        f,
+		ContentType: mime.TypeByExtension(path.Ext(name)),
+	})
+	return err
+}
+
+// downloads the file of the Drive to the local file
+func (s *syncer) download(ctx context.Context, name, loc string) error {
+	rc, err := s.d.GetCtx(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dir := filepath.Dir(loc)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so that a failed download does not leave a partial file
+	f, err := ioutil.TempFile(dir, ".sync-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), loc)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// deletes the orphaned files in the destination
+func (s *syncer) delete(ctx context.Context, rels []string, dst map[string]*syncFile) []*SyncAction {
+	actions := make([]*SyncAction, len(rels))
+	for n, rel := range rels {
+		actions[n] = &SyncAction{Op: SyncDelete, Path: rel}
+	}
+	if s.i.DryRun {
+		return actions
+	}
+
+	if s.i.Direction == SyncDownload {
+		for _, a := range actions {
+			if err := os.Remove(dst[a.Path].loc); err != nil && !os.IsNotExist(err) {
+				a.Err = err
+			}
+		}
+		return actions
+	}
+
+	if len(actions) == 0 {
+		return actions
+	}
+	names := make([]string, len(actions))
+	for n, a := range actions {
+		names[n] = dst[a.Path].loc
+	}
+	// the output has the results of the requests before a failed request
+	dr, err := s.d.DeleteManyCtx(ctx, names)
+	deleted := make(map[string]bool)
+	for _, name := range dr.Deleted {
+		deleted[name] = true
+	}
+	for n, a := range actions {
+		if msg, ok := dr.Failed[names[n]]; ok {
+			a.Err = fmt.Errorf("failed to delete %s: %v", names[n], msg)
+		} else if err != nil && !deleted[names[n]] {
+			a.Err = err
+		}
+	}
+	return actions
+}
@@ -0,0 +1,126 @@
+package drive
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// delay before the first retry of a failed part
+const partRetryBackoff = 100 * time.Millisecond
+
+// a part of a chunked upload
+type uploadJob struct {
+	part  int
+	chunk []byte
+}
+
+// a chunked upload of the parts returned by next
+type partUpload struct {
+	name        string
+	uploadId    string
+	contentType string
+	concurrency int
+	retries     int
+	// returns the next part to upload, or io.EOF if there are no more parts
+	next func() (uploadJob, error)
+	// called after a part has been uploaded, if set
+	done func(part int) error
+	// reports the progress after a part has been uploaded, if set
+	progress *progressTracker
+}
+
+// uploads the parts with a pool of workers
+//
+// At most one part per worker and the part being read are held in memory.
+// Returns the first error reading or uploading a part, the remaining
+// parts are not uploaded after an error.
+func (d *Drive) uploadParts(ctx context.Context, u *partUpload) error {
+	concurrency := u.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	jobs := make(chan uploadJob)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				err := d.uploadPartWithRetries(ctx, u, job)
+				if err == nil && u.done != nil {
+					err = u.done(job.part)
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				u.progress.add(int64(len(job.chunk)), job.part)
+			}
+		}()
+	}
+
+	for {
+		job, err := u.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+
+		select {
+		case jobs <- job:
+			continue
+		case <-ctx.Done():
+		}
+		fail(ctx.Err())
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
+
+// uploads a part, retrying it up to u.retries times if it fails
+//
+// Retries are delayed with an exponential backoff starting at 100 milliseconds.
+func (d *Drive) uploadPartWithRetries(ctx context.Context, u *partUpload, job uploadJob) error {
+	backoff := partRetryBackoff
+	for retry := 0; ; retry++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := d.uploadPart(ctx, u.name, job.chunk, u.uploadId, job.part, u.contentType)
+		if err == nil || retry >= u.retries || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,208 @@
+package drive
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/deta/deta-go/deta"
+)
+
+// FileWriter is a handle uploading a file to a Drive as it is written, returned by Create
+type FileWriter interface {
+	io.WriteCloser
+	// Name returns the name of the file.
+	Name() string
+	// CloseWithError aborts the upload, following writes return err.
+	CloseWithError(err error) error
+}
+
+// Writer is a FileWriter uploading a file to a Drive as it is written.
+//
+// The written content is buffered into parts of 10 MiB, each part is uploaded as it fills.
+// Close uploads the last part and finishes the upload, CloseWithError aborts the upload.
+//
+//	w, err := reports.Create("report.json.gz", "application/gzip")
+//	if err != nil {
+//		return err
+//	}
+//	gz := gzip.NewWriter(w)
+//	if err := json.NewEncoder(gz).Encode(report); err != nil {
+//		w.CloseWithError(err)
+//		return err
+//	}
+//	if err := gz.Close(); err != nil {
+//		w.CloseWithError(err)
+//		return err
+//	}
+//	return w.Close()
+type Writer struct {
+	d           *Drive
+	ctx         context.Context
+	name        string
+	contentType string
+	uploadId    string
+
+	mu   sync.Mutex
+	buf  []byte
+	part int
+	// error returned by writes, once an error occurred or the writer is closed
+	err error
+	// closed once the upload is finished or aborted
+	done chan struct{}
+}
+
+// Create starts an upload of a file to the Drive and returns a *Writer for its content.
+//
+// The upload is only finished when the Writer is closed.
+func (d *Drive) Create(name, contentType string) (FileWriter, error) {
+	return d.CreateCtx(context.Background(), name, contentType)
+}
+
+// CreateCtx is like Create but uses the provided context for the requests of the Writer.
+//
+// If the context is cancelled before the Writer is closed, the upload is aborted and the
+// following writes return the error of the context.
+func (d *Drive) CreateCtx(ctx context.Context, name, contentType string) (FileWriter, error) {
+	if name == "" {
+		return nil, deta.ErrEmptyName
+	}
+	uploadId, err := d.startUpload(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		d:           d,
+		ctx:         ctx,
+		name:        name,
+		contentType: contentType,
+		uploadId:    uploadId,
+		done:        make(chan struct{}),
+	}
+	if ctx.Done() != nil {
+		go w.watch()
+	}
+	return w, nil
+}
+
+// aborts the upload once the context is done, unless the upload is finished or aborted before
+func (w *Writer) watch() {
+	select {
+	case <-w.ctx.Done():
+	case <-w.done:
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.fail(w.ctx.Err())
+	}
+}
+
+// Name returns the name of the file.
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Write writes p to the file, uploading every filled part.
+//
+// If uploading a part fails, the upload is aborted and the error is returned by
+// the following writes.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if w.buf == nil {
+			w.buf = make([]byte, 0, uploadChunkSize)
+		}
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		written += n
+		p = p[n:]
+
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// uploads the buffered part, aborting the upload if it fails
+func (w *Writer) flush() error {
+	w.part++
+	err := w.ctx.Err()
+	if err == nil {
+		err = w.d.uploadPart(w.ctx, w.name, w.buf, w.uploadId, w.part, w.contentType)
+	}
+	if err != nil {
+		return w.fail(err)
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// aborts the upload after the error, returning an *AbortError if aborting fails too
+func (w *Writer) fail(err error) error {
+	if abortErr := w.abort(err); abortErr != nil {
+		err = &AbortError{Err: err, AbortErr: abortErr}
+		w.err = err
+	}
+	return err
+}
+
+// aborts the upload, the error is returned by following writes
+func (w *Writer) abort(err error) error {
+	w.err = err
+	w.buf = nil
+	close(w.done)
+	return w.d.abortUpload(w.name, w.uploadId)
+}
+
+// Close uploads the last part and finishes the upload.
+//
+// If the upload fails, it is aborted and the error is returned. Closing a Writer
+// again returns the error of the write that failed, or os.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	// an empty file is uploaded as a single empty part
+	if len(w.buf) > 0 || w.part == 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	if err := w.d.finishUpload(w.ctx, w.name, w.uploadId); err != nil {
+		return w.fail(err)
+	}
+	w.err = os.ErrClosed
+	w.buf = nil
+	close(w.done)
+	return nil
+}
+
+// CloseWithError aborts the upload, following writes return err.
+//
+// Returns the error aborting the upload, if any.
+func (w *Writer) CloseWithError(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return nil
+	}
+	if err == nil {
+		err = os.ErrClosed
+	}
+	return w.abort(err)
+}